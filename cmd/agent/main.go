@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/lib/eval"
+	c "github.com/apple5343/golangProjectV2/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// minBackoff and maxBackoff bound the wait before the agent registers again
+// after it lost the orchestrator.
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	hostname, _ := os.Hostname()
+	addr := flag.String("addr", "localhost:44041", "orchestrator gRPC address")
+	name := flag.String("name", hostname, "agent name")
+	slots := flag.Int("slots", 1, "number of subtasks computed in parallel")
+	flag.Parse()
+
+	conn, err := grpc.Dial(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := c.NewAgentClient(conn)
+	backoff := minBackoff
+	for {
+		res, err := client.RegisterAgent(context.Background(), &c.RegisterAgentRequest{Name: *name, Slots: int64(*slots)})
+		if status.Code(err) == codes.InvalidArgument {
+			return err
+		}
+		if err != nil {
+			log.Printf("failed to register: %v, retrying in %s", err, backoff)
+			time.Sleep(backoff)
+			backoff = min(2*backoff, maxBackoff)
+			continue
+		}
+		backoff = minBackoff
+		log.Printf("agent %s registered with id %d, %d slots", *name, res.AgentId, *slots)
+		err = serve(client, res.AgentId, *slots)
+		log.Printf("agent %d stopped: %v, registering again", res.AgentId, err)
+	}
+}
+
+// serve computes subtasks in every slot until one of them fails, then stops
+// the others and returns the error.
+func serve(client c.AgentClient, agentId int64, slots int) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, slots)
+	for i := 0; i < slots; i++ {
+		go func() {
+			errCh <- work(ctx, client, agentId)
+		}()
+	}
+	err := <-errCh
+	cancel()
+	for i := 1; i < slots; i++ {
+		<-errCh
+	}
+	return err
+}
+
+func work(ctx context.Context, client c.AgentClient, agentId int64) error {
+	for {
+		subtask, err := client.GetSubtask(ctx, &c.GetSubtaskRequest{AgentId: agentId})
+		if err != nil {
+			return err
+		}
+		if !subtask.Found {
+			time.Sleep(time.Second)
+			continue
+		}
+		lost, err := wait(ctx, client, agentId, subtask)
+		if err != nil {
+			return err
+		}
+		if lost {
+			log.Printf("subtask %d: lease lost, dropped", subtask.SubtaskId)
+			continue
+		}
+		req := &c.SendResultRequest{AgentId: agentId, SubtaskId: subtask.SubtaskId}
+		req.Result, err = eval.Eval(subtask.Expression)
+		if err != nil {
+			req.Error = err.Error()
+			log.Printf("subtask %d (%s): %v", subtask.SubtaskId, subtask.Expression, err)
+		}
+		if _, err := client.SendResult(ctx, req); err != nil {
+			if status.Code(err) != codes.FailedPrecondition {
+				return err
+			}
+			log.Printf("subtask %d: %v", subtask.SubtaskId, err)
+		}
+	}
+}
+
+// wait sleeps for the operation delay and heartbeats the lease meanwhile.
+// It returns true if the lease was lost.
+func wait(ctx context.Context, client c.AgentClient, agentId int64, subtask *c.GetSubtaskResponse) (bool, error) {
+	heartbeat := time.NewTicker(time.Duration(subtask.HeartbeatInterval) * time.Millisecond)
+	defer heartbeat.Stop()
+	d := time.Duration(subtask.Delay) * time.Second
+	if subtask.DelayMs > 0 {
+		d = time.Duration(subtask.DelayMs) * time.Millisecond
+	}
+	delay := time.After(d)
+	for {
+		select {
+		case <-delay:
+			return false, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-heartbeat.C:
+			res, err := client.Heartbeat(ctx, &c.HeartbeatRequest{AgentId: agentId, SubtaskId: subtask.SubtaskId})
+			if err != nil {
+				return false, err
+			}
+			if res.LeaseLost {
+				return true, nil
+			}
+		}
+	}
+}
@@ -1,14 +1,26 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/apple5343/golangProjectV2/internal/app"
 	"github.com/apple5343/golangProjectV2/internal/config"
 )
 
+const (
+	defaultGracePeriod = 30 * time.Second
+	// stopTimeout is how long the servers may take to stop once the running
+	// subtasks are finished.
+	stopTimeout = 5 * time.Second
+)
+
 func main() {
 	if err := run(); err != nil {
 		log.Fatal(err)
@@ -30,8 +42,37 @@ func run() error {
 		Handler: app,
 	}
 	fmt.Printf("Config: %+v\n", cfg)
-	if err := httpServer.ListenAndServe(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	// A second signal kills the process at once.
+	stop()
+	grace := cfg.Shutdown.GracePeriod
+	if grace <= 0 {
+		grace = defaultGracePeriod
+	}
+	log.Printf("shutting down, waiting up to %s for running subtasks", grace)
+	drainCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := app.Drain(drainCtx); err != nil {
+		log.Println(err)
+	}
+	stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(stopCtx); err != nil {
+		log.Println(err)
+	}
+	if err := app.Stop(stopCtx); err != nil {
 		return err
 	}
+	log.Println("stopped")
 	return nil
 }
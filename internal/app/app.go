@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -10,7 +11,7 @@ import (
 	"github.com/apple5343/golangProjectV2/internal/services/auth"
 	"github.com/apple5343/golangProjectV2/internal/services/calculator"
 	storage "github.com/apple5343/golangProjectV2/internal/storage/sqlite"
-	c "github.com/apple5343/grpc"
+	c "github.com/apple5343/golangProjectV2/proto"
 	"github.com/gorilla/mux"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -24,6 +25,8 @@ type Server struct {
 	GRPCServer *g.App
 	auth       c.AuthClient
 	calculator c.CalculatorClient
+	service    *calculator.Calculator
+	conn       *grpc.ClientConn
 }
 
 func New(storagePath string, cfg *config.Config) (*Server, error) {
@@ -38,19 +41,34 @@ func New(storagePath string, cfg *config.Config) (*Server, error) {
 		return &Server{}, err
 	}
 	auth := auth.New(db, cfg.SecretJWT, cfg.TokenTTL)
-	grpcapp := g.New(calculator, auth, cfg.GRPC.Port)
+	grpcapp := g.New(calculator, auth, calculator, cfg.GRPC.Port)
 	conn, err := grpc.Dial(fmt.Sprintf(":%d", grpcapp.Port), grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		return &Server{}, err
 	}
 	authClient := c.NewAuthClient(conn)
 	calcClient := c.NewCalculatorClient(conn)
-	s := &Server{db: db, config: cfg, router: router, GRPCServer: grpcapp, auth: authClient, calculator: calcClient, manager: manager}
+	s := &Server{db: db, config: cfg, router: router, GRPCServer: grpcapp, auth: authClient, calculator: calcClient, manager: manager, service: calculator, conn: conn}
 	calculator.ContinueCalculations()
 	s.SetupRoutes()
 	return s, nil
 }
 
+// Drain stops taking new tasks and waits until the subtasks being computed
+// are finished or ctx is done.
+func (s *Server) Drain(ctx context.Context) error {
+	return s.service.Shutdown(ctx)
+}
+
+// Stop disconnects the websocket clients, stops the gRPC server and closes
+// the storage.
+func (s *Server) Stop(ctx context.Context) error {
+	s.manager.Close()
+	s.conn.Close()
+	s.GRPCServer.Stop(ctx)
+	return s.db.Close()
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.router.ServeHTTP(w, r)
 }
@@ -1,6 +1,7 @@
 package grpc
 
 import (
+	"context"
 	"fmt"
 	"net"
 
@@ -13,9 +14,9 @@ type App struct {
 	Port       int
 }
 
-func New(calcService g.Calc, authSevice g.Auth, port int) *App {
+func New(calcService g.Calc, authSevice g.Auth, agentsService g.Agents, port int) *App {
 	gRPCServer := grpc.NewServer()
-	g.Register(gRPCServer, calcService, authSevice)
+	g.Register(gRPCServer, calcService, authSevice, agentsService)
 	return &App{
 		gRPCServer: gRPCServer,
 		Port:       port,
@@ -37,6 +38,17 @@ func (a *App) Run() error {
 	return nil
 }
 
-func (a *App) Stop() {
-	a.gRPCServer.GracefulStop()
+// Stop waits for the running calls to finish. Calls still running when ctx
+// is done are cancelled.
+func (a *App) Stop(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		a.gRPCServer.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		a.gRPCServer.Stop()
+	}
 }
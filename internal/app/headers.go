@@ -4,13 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"text/template"
 
 	"github.com/apple5343/golangProjectV2/internal/lib/jwt"
-	c "github.com/apple5343/grpc"
+	c "github.com/apple5343/golangProjectV2/proto"
 	"github.com/gorilla/sessions"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -56,6 +59,50 @@ func IsAdmin(r *http.Request, secret string) (bool, error) {
 	return isAdmin.(float64) == 1, nil
 }
 
+// checkAdmin writes the error response and returns false if the request was
+// not made by an admin.
+func checkAdmin(w http.ResponseWriter, r *http.Request, secret string) bool {
+	isAdmin, err := IsAdmin(r, secret)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return false
+	}
+	if !isAdmin {
+		http.Error(w, "Недостаточно прав", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// writeStatusError maps a gRPC error to the HTTP response.
+func writeStatusError(w http.ResponseWriter, err error) {
+	st, _ := status.FromError(err)
+	switch st.Code() {
+	case codes.Internal:
+		http.Error(w, st.Message(), http.StatusInternalServerError)
+	case codes.NotFound:
+		http.Error(w, st.Message(), http.StatusNotFound)
+	case codes.PermissionDenied:
+		http.Error(w, st.Message(), http.StatusForbidden)
+	case codes.ResourceExhausted:
+		setRetryAfter(w, st)
+		http.Error(w, st.Message(), http.StatusTooManyRequests)
+	case codes.Unavailable:
+		http.Error(w, st.Message(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, st.Message(), http.StatusBadRequest)
+	}
+}
+
+// setRetryAfter sets the Retry-After header from the retry delay of the status.
+func setRetryAfter(w http.ResponseWriter, st *status.Status) {
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(info.RetryDelay.AsDuration().Seconds()))))
+		}
+	}
+}
+
 func (s *Server) Register() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
@@ -133,7 +180,10 @@ func (s *Server) AddTask() http.HandlerFunc {
 			return
 		}
 		type Request struct {
-			Task string `json:"task"`
+			Task        string `json:"task"`
+			Priority    string `json:"priority"`
+			Deadline    string `json:"deadline"`
+			MaxDuration string `json:"maxDuration"`
 		}
 		var req Request
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -143,22 +193,157 @@ func (s *Server) AddTask() http.HandlerFunc {
 		id, err := GetToken(r, s.config.SecretJWT)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
 		}
-		task, err := s.calculator.AddTask(context.TODO(), &c.AddTaskRequest{UserId: int64(id), Task: req.Task})
+		task, err := s.calculator.AddTask(context.TODO(), &c.AddTaskRequest{UserId: int64(id), Task: req.Task, Priority: req.Priority, Deadline: req.Deadline, MaxDuration: req.MaxDuration})
 		if err != nil {
-			s, _ := status.FromError(err)
-			if s.Code() == codes.Internal {
-				http.Error(w, s.Message(), http.StatusInternalServerError)
-				return
-			} else {
-				http.Error(w, s.Message(), http.StatusUnauthorized)
-				return
-			}
+			writeStatusError(w, err)
+			return
 		}
 		w.Write([]byte(task.Task))
 	}
 }
 
+func (s *Server) CancelTask() http.HandlerFunc {
+	return s.changeTask(s.calculator.CancelTask)
+}
+
+func (s *Server) PauseTask() http.HandlerFunc {
+	return s.changeTask(s.calculator.PauseTask)
+}
+
+func (s *Server) ResumeTask() http.HandlerFunc {
+	return s.changeTask(s.calculator.ResumeTask)
+}
+
+func (s *Server) changeTask(change func(context.Context, *c.TaskRequest, ...grpc.CallOption) (*c.Empty, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		type Request struct {
+			Id int `json:"id"`
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id, err := GetToken(r, s.config.SecretJWT)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		_, err = change(context.TODO(), &c.TaskRequest{TaskId: int64(req.Id), UserId: int64(id)})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Write([]byte("OK"))
+	}
+}
+
+func (s *Server) AddSchedule() http.HandlerFunc {
+	return s.editSchedule(s.calculator.AddSchedule)
+}
+
+func (s *Server) UpdateSchedule() http.HandlerFunc {
+	return s.editSchedule(s.calculator.UpdateSchedule)
+}
+
+func (s *Server) editSchedule(edit func(context.Context, *c.ScheduleRequest, ...grpc.CallOption) (*c.ScheduleResponse, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		type Request struct {
+			Id         int    `json:"id"`
+			Expression string `json:"expression"`
+			Priority   string `json:"priority"`
+			RunAt      string `json:"runAt"`
+			Cron       string `json:"cron"`
+			CatchUp    string `json:"catchUp"`
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id, err := GetToken(r, s.config.SecretJWT)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		result, err := edit(context.TODO(), &c.ScheduleRequest{UserId: int64(id), ScheduleId: int64(req.Id), Expression: req.Expression,
+			Priority: req.Priority, RunAt: req.RunAt, Cron: req.Cron, CatchUp: req.CatchUp})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(result.Schedule))
+	}
+}
+
+func (s *Server) GetSchedules() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			return
+		}
+		id, err := GetToken(r, s.config.SecretJWT)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		result, err := s.calculator.GetSchedules(context.TODO(), &c.ScheduleRequest{UserId: int64(id)})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(result.Schedules))
+	}
+}
+
+func (s *Server) PauseSchedule() http.HandlerFunc {
+	return s.changeSchedule(s.calculator.PauseSchedule)
+}
+
+func (s *Server) ResumeSchedule() http.HandlerFunc {
+	return s.changeSchedule(s.calculator.ResumeSchedule)
+}
+
+func (s *Server) DeleteSchedule() http.HandlerFunc {
+	return s.changeSchedule(s.calculator.DeleteSchedule)
+}
+
+func (s *Server) changeSchedule(change func(context.Context, *c.ScheduleRequest, ...grpc.CallOption) (*c.Empty, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		type Request struct {
+			Id int `json:"id"`
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id, err := GetToken(r, s.config.SecretJWT)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		_, err = change(context.TODO(), &c.ScheduleRequest{ScheduleId: int64(req.Id), UserId: int64(id)})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Write([]byte("OK"))
+	}
+}
+
 func (s *Server) GetUserInfo() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "GET" {
@@ -225,12 +410,8 @@ func (s *Server) GetDelays() http.HandlerFunc {
 		if r.Method != "GET" {
 			return
 		}
-		isAdmin, err := IsAdmin(r, s.config.SecretJWT)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
-		}
-		if !isAdmin {
-			http.Error(w, "Недостаточно прав", http.StatusForbidden)
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
 		}
 		result, err := s.calculator.GetDelays(context.TODO(), &c.Empty{})
 		if err != nil {
@@ -247,39 +428,182 @@ func (s *Server) UpdateDelays() http.HandlerFunc {
 		if r.Method != "POST" {
 			return
 		}
-		isAdmin, err := IsAdmin(r, s.config.SecretJWT)
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		type Request struct {
+			Delays  map[string]json.RawMessage `json:"delays"`
+			Profile string                     `json:"profile"`
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		delays := ""
+		if len(req.Delays) > 0 {
+			str, err := json.Marshal(req.Delays)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			delays = string(str)
+		}
+		id, err := GetToken(r, s.config.SecretJWT)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
 		}
-		if !isAdmin {
-			http.Error(w, "Недостаточно прав", http.StatusForbidden)
+		_, err = s.calculator.UpdateDelays(context.TODO(), &c.UpdateDelaysRequest{Delays: delays, Profile: req.Profile, UserId: int64(id)})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+	}
+}
+
+func (s *Server) GetDelayHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		result, err := s.calculator.GetDelayHistory(context.TODO(), &c.Empty{})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(result.Versions))
+	}
+}
+
+func (s *Server) GetDelayOverrides() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		result, err := s.calculator.GetDelayOverrides(context.TODO(), &c.Empty{})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(result.Overrides))
+	}
+}
+
+// SetDelayOverride replaces the delay overrides of the group if it is given,
+// of the user otherwise.
+func (s *Server) SetDelayOverride() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
 		}
 		type Request struct {
-			Delays map[string]int `json:"delays"`
+			UserId int                        `json:"userId"`
+			Group  string                     `json:"group"`
+			Delays map[string]json.RawMessage `json:"delays"`
 		}
 		var req Request
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		m := make(map[string]int)
-		for k, v := range req.Delays {
-			if v < 0 {
-				http.Error(w, "Отрицательная задержка", http.StatusBadRequest)
+		delays := ""
+		if len(req.Delays) > 0 {
+			str, err := json.Marshal(req.Delays)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
-			m[k] = v
+			delays = string(str)
 		}
-
-		str, err := json.Marshal(m)
+		_, err := s.calculator.SetDelayOverride(context.TODO(), &c.DelayOverrideRequest{UserId: int64(req.UserId), Group: req.Group, Delays: delays})
 		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Write([]byte("OK"))
+	}
+}
+
+func (s *Server) SetUserDelayGroup() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		type Request struct {
+			UserId int    `json:"userId"`
+			Group  string `json:"group"`
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-		_, err = s.calculator.UpdateDelays(context.TODO(), &c.UpdateDelaysRequest{Delays: string(str)})
+		_, err := s.calculator.SetUserDelayGroup(context.TODO(), &c.SetUserDelayGroupRequest{UserId: int64(req.UserId), Group: req.Group})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeStatusError(w, err)
 			return
 		}
+		w.Write([]byte("OK"))
+	}
+}
+
+// GetUserDelays returns the delays the tasks of the signed in user run with.
+func (s *Server) GetUserDelays() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			return
+		}
+		id, err := GetToken(r, s.config.SecretJWT)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		result, err := s.calculator.GetUserDelays(context.TODO(), &c.GetUserDelaysRequest{UserId: int64(id)})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(result.Delays))
+	}
+}
+
+func (s *Server) SetDelayMode() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		type Request struct {
+			Mode string `json:"mode"`
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := s.calculator.SetDelayMode(context.TODO(), &c.SetDelayModeRequest{Mode: req.Mode}); err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Write([]byte("OK"))
 	}
 }
 
@@ -363,3 +687,299 @@ func (s *Server) Logout() http.HandlerFunc {
 		session.Save(r, w)
 	}
 }
+
+func (s *Server) AddWorkers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		type Request struct {
+			Count int    `json:"count"`
+			Pool  string `json:"pool"`
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err := s.calculator.AddWorkers(context.TODO(), &c.AddWorkersRequest{Count: int64(req.Count), Pool: req.Pool})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(result.Workers))
+	}
+}
+
+func (s *Server) GetScalingInfo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		result, err := s.calculator.GetScalingInfo(context.TODO(), &c.Empty{})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(result.Scaling))
+	}
+}
+
+func (s *Server) GetSchedulerStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		result, err := s.calculator.GetSchedulerStats(context.TODO(), &c.Empty{})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(result.Stats))
+	}
+}
+
+func (s *Server) GetStalledTasks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		result, err := s.calculator.GetStalledTasks(context.TODO(), &c.Empty{})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(result.Tasks))
+	}
+}
+
+func (s *Server) GetQueue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		result, err := s.calculator.GetQueue(context.TODO(), &c.Empty{})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(result.Queue))
+	}
+}
+
+func (s *Server) GetDeadLetters() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		result, err := s.calculator.GetDeadLetters(context.TODO(), &c.Empty{})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(result.Letters))
+	}
+}
+
+func (s *Server) RetryDeadLetter() http.HandlerFunc {
+	return s.changeDeadLetter(s.calculator.RetryDeadLetter)
+}
+
+func (s *Server) DiscardDeadLetter() http.HandlerFunc {
+	return s.changeDeadLetter(s.calculator.DiscardDeadLetter)
+}
+
+func (s *Server) changeDeadLetter(change func(context.Context, *c.DeadLetterRequest, ...grpc.CallOption) (*c.Empty, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		type Request struct {
+			Id int `json:"id"`
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id, err := GetToken(r, s.config.SecretJWT)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if _, err := change(context.TODO(), &c.DeadLetterRequest{Id: int64(req.Id), UserId: int64(id)}); err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Write([]byte("OK"))
+	}
+}
+
+func (s *Server) SetQuorum() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		type Request struct {
+			All        bool     `json:"all"`
+			Operations []string `json:"operations"`
+			Size       int      `json:"size"`
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, err := s.calculator.SetQuorum(context.TODO(), &c.SetQuorumRequest{All: req.All, Operations: req.Operations, Size: int64(req.Size)})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Write([]byte("OK"))
+	}
+}
+
+func (s *Server) SetUserWeight() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		type Request struct {
+			UserId int `json:"userId"`
+			Weight int `json:"weight"`
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, err := s.calculator.SetUserWeight(context.TODO(), &c.SetUserWeightRequest{UserId: int64(req.UserId), Weight: int64(req.Weight)})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Write([]byte("OK"))
+	}
+}
+
+func (s *Server) SetDefaultTimeout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		type Request struct {
+			MaxDuration string `json:"maxDuration"`
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, err := s.calculator.SetDefaultTimeout(context.TODO(), &c.SetDefaultTimeoutRequest{MaxDuration: req.MaxDuration})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Write([]byte("OK"))
+	}
+}
+
+func (s *Server) SetUserQuota() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		type Request struct {
+			UserId      int `json:"userId"`
+			Concurrent  int `json:"concurrent"`
+			PerHour     int `json:"perHour"`
+			DelayPerDay int `json:"delayPerDay"`
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, err := s.calculator.SetUserQuota(context.TODO(), &c.SetUserQuotaRequest{UserId: int64(req.UserId), Concurrent: int64(req.Concurrent), PerHour: int64(req.PerHour), DelayPerDay: int64(req.DelayPerDay)})
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Write([]byte("OK"))
+	}
+}
+
+func (s *Server) RemoveWorker() http.HandlerFunc {
+	return s.changeWorker(s.calculator.RemoveWorker)
+}
+
+func (s *Server) DrainWorker() http.HandlerFunc {
+	return s.changeWorker(s.calculator.DrainWorker)
+}
+
+func (s *Server) UnquarantineWorker() http.HandlerFunc {
+	return s.changeWorker(s.calculator.UnquarantineWorker)
+}
+
+func (s *Server) changeWorker(change func(context.Context, *c.WorkerRequest, ...grpc.CallOption) (*c.Empty, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			return
+		}
+		if !checkAdmin(w, r, s.config.SecretJWT) {
+			return
+		}
+		type Request struct {
+			Id int `json:"id"`
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := change(context.TODO(), &c.WorkerRequest{WorkerId: int64(req.Id)}); err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		w.Write([]byte("OK"))
+	}
+}
@@ -10,12 +10,42 @@ func (s *Server) SetupRoutes() {
 	s.router.Handle("/register", s.Register())
 	s.router.Handle("/getInfo", s.GetUserInfo())
 	s.router.Handle("/addTask", s.AddTask())
+	s.router.Handle("/cancelTask", s.CancelTask())
+	s.router.Handle("/pauseTask", s.PauseTask())
+	s.router.Handle("/resumeTask", s.ResumeTask())
+	s.router.Handle("/addSchedule", s.AddSchedule())
+	s.router.Handle("/getSchedules", s.GetSchedules())
+	s.router.Handle("/updateSchedule", s.UpdateSchedule())
+	s.router.Handle("/pauseSchedule", s.PauseSchedule())
+	s.router.Handle("/resumeSchedule", s.ResumeSchedule())
+	s.router.Handle("/deleteSchedule", s.DeleteSchedule())
 	s.router.Handle("/getTasks", s.GetTasks())
 	s.router.Handle("/getTask", s.GetTask())
 	s.router.Handle("/getWorkersInfo", s.GetWorkersInfo())
 	s.router.Handle("/logout", s.Logout())
 	s.router.Handle("/updateDelays", s.UpdateDelays())
 	s.router.Handle("/getDelays", s.GetDelays())
+	s.router.Handle("/getDelayHistory", s.GetDelayHistory())
+	s.router.Handle("/setDelayMode", s.SetDelayMode())
+	s.router.Handle("/getDelayOverrides", s.GetDelayOverrides())
+	s.router.Handle("/setDelayOverride", s.SetDelayOverride())
+	s.router.Handle("/setUserDelayGroup", s.SetUserDelayGroup())
+	s.router.Handle("/getUserDelays", s.GetUserDelays())
+	s.router.Handle("/addWorkers", s.AddWorkers())
+	s.router.Handle("/removeWorker", s.RemoveWorker())
+	s.router.Handle("/drainWorker", s.DrainWorker())
+	s.router.Handle("/unquarantineWorker", s.UnquarantineWorker())
+	s.router.Handle("/getScalingInfo", s.GetScalingInfo())
+	s.router.Handle("/getSchedulerStats", s.GetSchedulerStats())
+	s.router.Handle("/getStalledTasks", s.GetStalledTasks())
+	s.router.Handle("/getQueue", s.GetQueue())
+	s.router.Handle("/getDeadLetters", s.GetDeadLetters())
+	s.router.Handle("/retryDeadLetter", s.RetryDeadLetter())
+	s.router.Handle("/discardDeadLetter", s.DiscardDeadLetter())
+	s.router.Handle("/setQuorum", s.SetQuorum())
+	s.router.Handle("/setUserWeight", s.SetUserWeight())
+	s.router.Handle("/setUserQuota", s.SetUserQuota())
+	s.router.Handle("/setDefaultTimeout", s.SetDefaultTimeout())
 	s.router.Handle("/ws", s.manager.ServeWs(store))
 	s.router.HandleFunc("/", s.Home())
 }
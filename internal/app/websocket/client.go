@@ -3,10 +3,18 @@ package websocket
 import (
 	"encoding/json"
 	"log"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// closeTimeout is how long writing the close frame may take.
+const closeTimeout = time.Second
+
+// egressSize is how many events may wait for a slow client before further
+// events for it are dropped.
+const egressSize = 64
+
 type ClientList map[*Client]bool
 type OnlineCliets map[int]*Client
 
@@ -22,21 +30,33 @@ func NewClient(conn *websocket.Conn, manager *Manager, id int, isAdmin bool) *Cl
 	return &Client{
 		connection: conn,
 		manager:    manager,
-		egress:     make(chan Event),
+		egress:     make(chan Event, egressSize),
 		id:         id,
 		isAdmin:    isAdmin,
 	}
 }
 
+// offer queues the event for the client without waiting, so a slow client
+// does not hold up the others. The event is dropped if the queue is full.
+func (c *Client) offer(event Event) {
+	select {
+	case c.egress <- event:
+	default:
+		log.Printf("client %d is too slow, %s event dropped", c.id, event.Type)
+	}
+}
+
 func (c *Client) WriteMessages() {
 	defer func() {
 		c.manager.removeClient(c)
+		c.manager.writers.Done()
 	}()
 	for {
 		select {
 		case message, ok := <-c.egress:
 			if !ok {
-				if err := c.connection.WriteMessage(websocket.CloseMessage, nil); err != nil {
+				message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down")
+				if err := c.connection.WriteControl(websocket.CloseMessage, message, time.Now().Add(closeTimeout)); err != nil {
 					log.Println(err)
 				}
 				return
@@ -16,15 +16,19 @@ type EventHandler func(event Event, c *Client) error
 const (
 	EventTaskUpdate   = "update task"
 	EventWorkerUpdate = "update worker"
+	EventScaling      = "scaling"
+	EventTaskStalled  = "task stalled"
+	EventQueue        = "queue"
 )
 
-func UpdateTaskMessage(userId, taskId, isDone int, lastPing, result string) *Event {
+func UpdateTaskMessage(userId, taskId, isDone int, lastPing, result, status string) *Event {
 	type UpdateTask struct {
 		UserId   int    `json:"userId"`
 		TaskId   int    `json:"taskId"`
 		LastPing string `json:"lastPing"`
 		IsDone   int    `json:"isDone"`
 		Result   string `json:"result"`
+		Status   string `json:"status"`
 	}
 	task := UpdateTask{
 		TaskId:   taskId,
@@ -32,6 +36,7 @@ func UpdateTaskMessage(userId, taskId, isDone int, lastPing, result string) *Eve
 		IsDone:   isDone,
 		Result:   result,
 		UserId:   userId,
+		Status:   status,
 	}
 	message, err := json.Marshal(task)
 	if err != nil {
@@ -44,18 +49,30 @@ func UpdateTaskMessage(userId, taskId, isDone int, lastPing, result string) *Eve
 	}
 }
 
-func UpdateWorkerMessage(workerId int, state, exp string, taskId int) *Event {
+// WorkerStats are the counters of a built-in worker.
+type WorkerStats struct {
+	Started     string  `json:"started"`
+	Completed   int     `json:"completed"`
+	BusyTime    string  `json:"busyTime"`
+	Utilization float64 `json:"utilization"`
+	LastError   string  `json:"lastError"`
+	Crashes     int     `json:"crashes"`
+}
+
+func UpdateWorkerMessage(workerId int, state, exp string, taskId int, stats *WorkerStats) *Event {
 	type UpdateWorker struct {
-		Id     int    `json:"id"`
-		State  string `json:"state"`
-		Exp    string `json:"exp"`
-		TaskId int    `json:"taskId"`
+		Id     int          `json:"id"`
+		State  string       `json:"state"`
+		Exp    string       `json:"exp"`
+		TaskId int          `json:"taskId"`
+		Stats  *WorkerStats `json:"stats,omitempty"`
 	}
 	update := UpdateWorker{
 		Id:     workerId,
 		State:  state,
 		Exp:    exp,
 		TaskId: taskId,
+		Stats:  stats,
 	}
 	message, err := json.Marshal(update)
 	if err != nil {
@@ -67,3 +84,105 @@ func UpdateWorkerMessage(workerId int, state, exp string, taskId int) *Event {
 		To:      0,
 	}
 }
+
+func UpdateScalingMessage(time, action string, workers, depth int, wait, reason string) *Event {
+	type Scaling struct {
+		Time    string `json:"time"`
+		Action  string `json:"action"`
+		Workers int    `json:"workers"`
+		Depth   int    `json:"depth"`
+		Wait    string `json:"wait"`
+		Reason  string `json:"reason"`
+	}
+	update := Scaling{
+		Time:    time,
+		Action:  action,
+		Workers: workers,
+		Depth:   depth,
+		Wait:    wait,
+		Reason:  reason,
+	}
+	message, err := json.Marshal(update)
+	if err != nil {
+		log.Println(err)
+	}
+	return &Event{
+		Type:    EventScaling,
+		Message: string(message),
+		To:      0,
+	}
+}
+
+// TaskStalledMessage alerts the admins that a task stopped making progress or
+// recovered from a stall.
+func TaskStalledMessage(taskId, userId int, state, time, lastPing, threshold string, attempts int) *Event {
+	type Stall struct {
+		TaskId    int    `json:"taskId"`
+		UserId    int    `json:"userId"`
+		State     string `json:"state"`
+		Time      string `json:"time"`
+		LastPing  string `json:"lastPing"`
+		Threshold string `json:"threshold"`
+		Attempts  int    `json:"attempts"`
+	}
+	update := Stall{
+		TaskId:    taskId,
+		UserId:    userId,
+		State:     state,
+		Time:      time,
+		LastPing:  lastPing,
+		Threshold: threshold,
+		Attempts:  attempts,
+	}
+	message, err := json.Marshal(update)
+	if err != nil {
+		log.Println(err)
+	}
+	return &Event{
+		Type:    EventTaskStalled,
+		Message: string(message),
+		To:      0,
+	}
+}
+
+// QueuedSubtask is a subtask waiting in the queue for a worker.
+type QueuedSubtask struct {
+	Key        int    `json:"key"`
+	TaskId     int    `json:"taskId"`
+	UserId     int    `json:"userId"`
+	Operation  string `json:"operation"`
+	Expression string `json:"expression"`
+	Pool       string `json:"pool"`
+	Priority   string `json:"priority"`
+	Queued     string `json:"queued"`
+	Wait       string `json:"wait"`
+	WaitMs     int64  `json:"waitMs"`
+	// Next is set for the subtask the pool offers its workers now.
+	Next bool `json:"next"`
+}
+
+// QueueSnapshot is the state of the queue: the waiting subtasks, the oldest
+// first, how many subtasks of each operation wait and the age of the oldest.
+type QueueSnapshot struct {
+	Time         string          `json:"time"`
+	Total        int             `json:"total"`
+	Depth        map[string]int  `json:"depth"`
+	OldestWait   string          `json:"oldestWait"`
+	OldestWaitMs int64           `json:"oldestWaitMs"`
+	Subtasks     []QueuedSubtask `json:"subtasks"`
+	// Truncated is set if more subtasks wait than are listed.
+	Truncated bool `json:"truncated"`
+}
+
+// QueueMessage sends the admins the state of the queue.
+func QueueMessage(snapshot *QueueSnapshot) *Event {
+	message, err := json.Marshal(snapshot)
+	if err != nil {
+		log.Println(err)
+	}
+	return &Event{
+		Type:    EventQueue,
+		Message: string(message),
+		To:      0,
+	}
+}
@@ -23,6 +23,10 @@ type Manager struct {
 	mu              sync.RWMutex
 	secret          string
 	ListenUpdatesCh chan Event
+	// closed is set when the clients are disconnected on shutdown, later
+	// events are dropped.
+	closed  bool
+	writers sync.WaitGroup
 }
 
 func NewManager(secret string) *Manager {
@@ -44,19 +48,25 @@ func (m *Manager) listenUpdates() {
 			if !ok {
 				return
 			}
+			m.mu.RLock()
+			if m.closed {
+				m.mu.RUnlock()
+				continue
+			}
 			if event.Type == EventTaskUpdate {
 				client, ok := m.online[event.To]
 				if ok {
-					client.egress <- event
+					client.offer(event)
 				}
-			} else if event.Type == EventWorkerUpdate {
+			} else {
 				for k := range m.online {
 					client := m.online[k]
 					if client.isAdmin {
-						client.egress <- event
+						client.offer(event)
 					}
 				}
 			}
+			m.mu.RUnlock()
 		}
 	}
 }
@@ -119,6 +129,24 @@ func (m *Manager) addClient(client *Client) {
 	defer m.mu.Unlock()
 	m.clients[client] = true
 	m.online[client.id] = client
+	m.writers.Add(1)
+	if m.closed {
+		close(client.egress)
+	}
+}
+
+// Close sends a close frame to every client and waits until they are
+// disconnected.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		for client := range m.clients {
+			close(client.egress)
+		}
+	}
+	m.mu.Unlock()
+	m.writers.Wait()
 }
 
 func (m *Manager) removeClient(client *Client) {
@@ -8,12 +8,23 @@ import (
 )
 
 type Config struct {
-	StoragePath   string        `yaml:"storage_path" env-required:"true"`
-	GRPC          GRPCConfig    `yaml:"grpc"`
-	Address       string        `yaml:"address"  env-default:"localhost:8080"`
-	TokenTTL      time.Duration `yaml:"token_ttl" env-default:"1h"`
-	SecretJWT     string        `yaml:"secret_jwt"`
-	SecretStorage string        `yaml:"secret_storage"`
+	StoragePath   string            `yaml:"storage_path" env-required:"true"`
+	GRPC          GRPCConfig        `yaml:"grpc"`
+	Address       string            `yaml:"address"  env-default:"localhost:8080"`
+	TokenTTL      time.Duration     `yaml:"token_ttl" env-default:"1h"`
+	SecretJWT     string            `yaml:"secret_jwt"`
+	SecretStorage string            `yaml:"secret_storage"`
+	Workers       WorkersConfig     `yaml:"workers"`
+	Agents        AgentsConfig      `yaml:"agents"`
+	Leases        LeasesConfig      `yaml:"leases"`
+	Speculation   SpeculationConfig `yaml:"speculation"`
+	Quorum        QuorumConfig      `yaml:"quorum"`
+	Quotas        QuotasConfig      `yaml:"quotas"`
+	Tasks         TasksConfig       `yaml:"tasks"`
+	Shutdown      ShutdownConfig    `yaml:"shutdown"`
+	Watchdog      WatchdogConfig    `yaml:"watchdog"`
+	Retries       RetriesConfig     `yaml:"retries"`
+	Inspector     InspectorConfig   `yaml:"inspector"`
 }
 
 type GRPCConfig struct {
@@ -21,6 +32,95 @@ type GRPCConfig struct {
 	Timeout time.Duration `yaml:"timeout"`
 }
 
+type WorkersConfig struct {
+	Count     int             `yaml:"count"`
+	Autoscale AutoscaleConfig `yaml:"autoscale"`
+	Pools     []PoolConfig    `yaml:"pools"`
+}
+
+type PoolConfig struct {
+	Name       string   `yaml:"name"`
+	Operations []string `yaml:"operations"`
+	Count      int      `yaml:"count"`
+	Overflow   bool     `yaml:"overflow"`
+}
+
+type AutoscaleConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Min            int           `yaml:"min"`
+	Max            int           `yaml:"max"`
+	Interval       time.Duration `yaml:"interval"`
+	Cooldown       time.Duration `yaml:"cooldown"`
+	MaxWait        time.Duration `yaml:"max_wait"`
+	QueuePerWorker int           `yaml:"queue_per_worker"`
+}
+
+type AgentsConfig struct {
+	PollTimeout  time.Duration `yaml:"poll_timeout"`
+	OfflineAfter time.Duration `yaml:"offline_after"`
+}
+
+type LeasesConfig struct {
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type SpeculationConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Factor   float64       `yaml:"factor"`
+	Slack    time.Duration `yaml:"slack"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+type QuorumConfig struct {
+	All             bool     `yaml:"all"`
+	Operations      []string `yaml:"operations"`
+	Size            int      `yaml:"size"`
+	QuarantineAfter int      `yaml:"quarantine_after"`
+}
+
+// QuotasConfig holds the global limits for every user, 0 means no limit.
+// DelayPerDay is in delay seconds of the computed operations.
+type QuotasConfig struct {
+	Concurrent  int `yaml:"concurrent"`
+	PerHour     int `yaml:"per_hour"`
+	DelayPerDay int `yaml:"delay_per_day"`
+}
+
+// TasksConfig holds the maximum duration of tasks submitted without a
+// deadline, 0 means no limit.
+type TasksConfig struct {
+	MaxDuration time.Duration `yaml:"max_duration"`
+}
+
+// ShutdownConfig holds how long running subtasks may take to finish on
+// shutdown before they are interrupted.
+type ShutdownConfig struct {
+	GracePeriod time.Duration `yaml:"grace_period"`
+}
+
+// WatchdogConfig holds how long a processing task may go without a computed
+// subtask: Factor times the longest delay of its pending subtasks plus Slack.
+type WatchdogConfig struct {
+	Interval time.Duration `yaml:"interval"`
+	Factor   float64       `yaml:"factor"`
+	Slack    time.Duration `yaml:"slack"`
+}
+
+// RetriesConfig holds how often a failed subtask is queued again. The wait
+// before a retry starts at Backoff and doubles up to MaxBackoff.
+type RetriesConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+}
+
+// InspectorConfig holds how often the admins get the state of the queue and
+// how many queued subtasks it lists at most.
+type InspectorConfig struct {
+	Interval time.Duration `yaml:"interval"`
+	MaxItems int           `yaml:"max_items"`
+}
+
 func InitConfig(path string) (*Config, error) {
 	file, err := os.ReadFile(path)
 	if err != nil {
@@ -0,0 +1,42 @@
+package models
+
+// Kinds of delay distributions.
+const (
+	DelayFixed       = "fixed"
+	DelayUniform     = "uniform"
+	DelayNormal      = "normal"
+	DelayExponential = "exponential"
+)
+
+// Delay is the distribution the delay of an operation is drawn from, all
+// values are in milliseconds. Fixed uses Value, uniform draws between Min
+// and Max, normal uses Mean and StdDev and exponential uses Mean. Min is
+// also the lower bound of normal and exponential delays.
+type Delay struct {
+	Kind   string `json:"kind"`
+	Value  int    `json:"value,omitempty"`
+	Min    int    `json:"min,omitempty"`
+	Max    int    `json:"max,omitempty"`
+	Mean   int    `json:"mean,omitempty"`
+	StdDev int    `json:"stdDev,omitempty"`
+}
+
+// DelayVersion is the delays of all operations as they were set by Author.
+// Every change of the delays is a new version, tasks remember the version
+// they started with.
+type DelayVersion struct {
+	ID      int              `json:"id"`
+	Delays  map[string]Delay `json:"delays"`
+	Profile string           `json:"profile"`
+	Author  string           `json:"author"`
+	Created string           `json:"created"`
+}
+
+// DelayOverrides are delays that replace the delays in effect for some
+// users. An override of the user wins over the override of the user's group
+// (Members), operations without an override keep the delays in effect.
+type DelayOverrides struct {
+	Users   map[int]map[string]Delay    `json:"users"`
+	Groups  map[string]map[string]Delay `json:"groups"`
+	Members map[int]string              `json:"members"`
+}
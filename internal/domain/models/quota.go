@@ -0,0 +1,15 @@
+package models
+
+// Quota limits the compute a user may use. A zero limit means unlimited.
+// In a per-user override -1 means the global limit applies.
+type Quota struct {
+	Concurrent  int
+	PerHour     int
+	DelayPerDay int
+}
+
+// TaskUsage is what a submitted task counts against the quotas.
+type TaskUsage struct {
+	Created string
+	Cost    int
+}
@@ -0,0 +1,19 @@
+package models
+
+// Schedule creates tasks from the expression at RunAt or on the Cron
+// recurrence. Times are in the "2006-01-02 15:04:05" format.
+type Schedule struct {
+	ID         int    `json:"id"`
+	UserID     int    `json:"userId"`
+	Expression string `json:"expression"`
+	Priority   string `json:"priority"`
+	RunAt      string `json:"runAt,omitempty"`
+	Cron       string `json:"cron,omitempty"`
+	CatchUp    string `json:"catchUp"`
+	Status     string `json:"status"`
+	NextRun    string `json:"nextRun"`
+	LastRun    string `json:"lastRun"`
+	LastTaskID int    `json:"lastTaskId"`
+	Note       string `json:"note"`
+	Created    string `json:"created"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Task statuses. Completed, failed, cancelled and timed out tasks are
+// finished.
+const (
+	StatusQueued     = "queued"
+	StatusBlocked    = "blocked"
+	StatusProcessing = "processing"
+	StatusPaused     = "paused"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+	StatusCancelled  = "cancelled"
+	StatusTimedOut   = "timed_out"
+)
+
+// StatusChange is an entry of the status history of a task. By is who or
+// what made the change, e.g. "user 2", "worker 5" or "deadline".
+type StatusChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	By   string `json:"by"`
+	Time string `json:"time"`
+}
+
+// TaskInfo is the state of a task that is not finished yet.
+type TaskInfo struct {
+	ID       int
+	UserID   int
+	Status   string
+	LastStep string
+	Deadline string
+	Created  string
+	LastPing string
+}
+
+// JournalEntry is a subtask of the current round of a task. Round is the
+// expression the round was split from, Done is set once Result is known.
+type JournalEntry struct {
+	TaskID   int
+	SymbolID int
+	Round    string
+	Value    string
+	Result   string
+	Done     bool
+}
+
+// StepTiming tells who computed a subtask and when. Dispatched is when the
+// subtask was queued, Started when the worker or agent took it. Delay is the
+// operation delay drawn for this computation.
+type StepTiming struct {
+	WorkerID   int
+	Agent      string
+	Dispatched time.Time
+	Started    time.Time
+	Finished   time.Time
+	Delay      time.Duration
+}
+
+// DeadLetter is a subtask that failed more often than the retry policy
+// allows. Its task is failed until the dead letter is retried.
+type DeadLetter struct {
+	ID        int    `json:"id"`
+	TaskID    int    `json:"taskId"`
+	UserID    int    `json:"userId"`
+	Value     string `json:"value"`
+	Operation string `json:"operation"`
+	Attempts  int    `json:"attempts"`
+	Error     string `json:"error"`
+	Created   string `json:"created"`
+}
@@ -5,23 +5,66 @@ import (
 	"encoding/json"
 	"errors"
 	"regexp"
+	"time"
 
+	"github.com/apple5343/golangProjectV2/internal/app/websocket"
+	"github.com/apple5343/golangProjectV2/internal/domain/models"
 	"github.com/apple5343/golangProjectV2/internal/services/auth"
 	"github.com/apple5343/golangProjectV2/internal/services/calculator"
 	storage "github.com/apple5343/golangProjectV2/internal/storage/sqlite"
-	c "github.com/apple5343/grpc"
+	c "github.com/apple5343/golangProjectV2/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 type Calc interface {
-	NewTask(int, string) (*calculator.Task, error)
+	NewTask(int, string, calculator.TaskOptions) (*calculator.Task, error)
+	CancelTask(int, int) error
+	PauseTask(int, int) error
+	ResumeTask(int, int) error
+	AddSchedule(int, calculator.ScheduleOptions) (models.Schedule, error)
+	GetSchedules(int) ([]models.Schedule, error)
+	UpdateSchedule(int, int, calculator.ScheduleOptions) (models.Schedule, error)
+	PauseSchedule(int, int) error
+	ResumeSchedule(int, int) error
+	DeleteSchedule(int, int) error
 	GetAllTasks(int64) ([]map[string]interface{}, error)
 	GetWorkersInfo() ([]map[string]interface{}, error)
-	UpdateDelays(map[string]int) error
-	GetDelays() (map[string]int, error)
+	UpdateDelays(map[string]models.Delay, string, int) error
+	GetDelays() (map[string]interface{}, error)
+	GetDelayHistory() ([]models.DelayVersion, error)
+	SetDelayMode(string) error
+	GetDelayOverrides() models.DelayOverrides
+	SetUserDelays(int, map[string]models.Delay) error
+	SetGroupDelays(string, map[string]models.Delay) error
+	SetUserDelayGroup(int, string) error
+	GetUserDelays(int) map[string]interface{}
 	GetTaskById(int64, int64) (string, error)
+	AddWorkers(string, int) ([]int, error)
+	RemoveWorker(int) error
+	DrainWorker(int) error
+	Unquarantine(int) error
+	GetScalingInfo() (map[string]interface{}, error)
+	GetSchedulerStats() (map[string]interface{}, error)
+	GetStalledTasks() []calculator.Stall
+	GetQueue() websocket.QueueSnapshot
+	GetDeadLetters() ([]models.DeadLetter, error)
+	RetryDeadLetter(int, int) error
+	DiscardDeadLetter(int) error
+	SetQuorum(bool, []string, int) error
+	SetUserWeight(int, int) error
+	SetUserQuota(int, int, int, int) error
+	SetDefaultTimeout(time.Duration) error
+}
+
+type Agents interface {
+	RegisterAgent(string, int) (int, error)
+	GetSubtask(context.Context, int) (*calculator.RemoteSubtask, error)
+	Heartbeat(int, int) (bool, error)
+	SendResult(int, int, string, string) error
 }
 
 type Auth interface {
@@ -34,13 +77,16 @@ type Auth interface {
 type serverAPI struct {
 	c.UnimplementedAuthServer
 	c.UnimplementedCalculatorServer
-	Calc Calc
-	Auth Auth
+	c.UnimplementedAgentServer
+	Calc   Calc
+	Auth   Auth
+	Agents Agents
 }
 
-func Register(gRPCServer *grpc.Server, calc Calc, auth Auth) {
+func Register(gRPCServer *grpc.Server, calc Calc, auth Auth, agents Agents) {
 	c.RegisterAuthServer(gRPCServer, &serverAPI{Calc: calc, Auth: auth})
 	c.RegisterCalculatorServer(gRPCServer, &serverAPI{Calc: calc, Auth: auth})
+	c.RegisterAgentServer(gRPCServer, &serverAPI{Agents: agents})
 }
 
 func (s *serverAPI) GetTask(ctx context.Context, in *c.GetTaskRequest) (*c.GetTaskResponse, error) {
@@ -66,28 +112,98 @@ func (s *serverAPI) GetDelays(ctx context.Context, in *c.Empty) (*c.GetDelaysRes
 	return &c.GetDelaysResponse{Delays: string(str)}, nil
 }
 
+// UpdateDelays takes the delays as a JSON object by operation. A number is a
+// fixed delay in seconds, an object is a distribution in milliseconds.
 func (s *serverAPI) UpdateDelays(ctx context.Context, in *c.UpdateDelaysRequest) (*c.Empty, error) {
-	type Request struct {
-		Plus           int `json:"plus"`
-		Minus          int `json:"minus"`
-		Multiplication int `json:"multiplication"`
-		Division       int `json:"division"`
+	delays, err := parseDelays(in.Delays)
+	if err != nil {
+		return &c.Empty{}, status.Error(codes.InvalidArgument, err.Error())
 	}
-	var req Request
-	err := json.Unmarshal([]byte(in.Delays), &req)
+	return &c.Empty{}, delayError(s.Calc.UpdateDelays(delays, in.Profile, int(in.UserId)))
+}
+
+func (s *serverAPI) GetDelayHistory(ctx context.Context, in *c.Empty) (*c.GetDelayHistoryResponse, error) {
+	versions, err := s.Calc.GetDelayHistory()
+	if err != nil {
+		return &c.GetDelayHistoryResponse{}, status.Error(codes.Internal, "failed to read")
+	}
+	js, err := json.Marshal(versions)
+	if err != nil {
+		return &c.GetDelayHistoryResponse{}, status.Error(codes.Internal, "failed to read")
+	}
+	return &c.GetDelayHistoryResponse{Versions: string(js)}, nil
+}
+
+func (s *serverAPI) SetDelayMode(ctx context.Context, in *c.SetDelayModeRequest) (*c.Empty, error) {
+	return &c.Empty{}, delayError(s.Calc.SetDelayMode(in.Mode))
+}
+
+func (s *serverAPI) GetDelayOverrides(ctx context.Context, in *c.Empty) (*c.GetDelayOverridesResponse, error) {
+	js, err := json.Marshal(s.Calc.GetDelayOverrides())
+	if err != nil {
+		return &c.GetDelayOverridesResponse{}, status.Error(codes.Internal, "failed to read")
+	}
+	return &c.GetDelayOverridesResponse{Overrides: string(js)}, nil
+}
+
+func (s *serverAPI) SetDelayOverride(ctx context.Context, in *c.DelayOverrideRequest) (*c.Empty, error) {
+	delays, err := parseDelays(in.Delays)
 	if err != nil {
 		return &c.Empty{}, status.Error(codes.InvalidArgument, err.Error())
 	}
-	m := make(map[string]int)
-	m["plus"] = req.Plus
-	m["minus"] = req.Minus
-	m["multiplication"] = req.Multiplication
-	m["division"] = req.Division
-	err = s.Calc.UpdateDelays(m)
+	if in.Group != "" {
+		return &c.Empty{}, delayError(s.Calc.SetGroupDelays(in.Group, delays))
+	}
+	return &c.Empty{}, delayError(s.Calc.SetUserDelays(int(in.UserId), delays))
+}
+
+func (s *serverAPI) SetUserDelayGroup(ctx context.Context, in *c.SetUserDelayGroupRequest) (*c.Empty, error) {
+	return &c.Empty{}, delayError(s.Calc.SetUserDelayGroup(int(in.UserId), in.Group))
+}
+
+func (s *serverAPI) GetUserDelays(ctx context.Context, in *c.GetUserDelaysRequest) (*c.GetDelaysResponse, error) {
+	js, err := json.Marshal(s.Calc.GetUserDelays(int(in.UserId)))
 	if err != nil {
-		return &c.Empty{}, status.Error(codes.Internal, "failed to update")
+		return &c.GetDelaysResponse{}, status.Error(codes.Internal, "failed to read")
 	}
-	return &c.Empty{}, nil
+	return &c.GetDelaysResponse{Delays: string(js)}, nil
+}
+
+func parseDelays(in string) (map[string]models.Delay, error) {
+	delays := make(map[string]models.Delay)
+	if in == "" {
+		return delays, nil
+	}
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(in), &req); err != nil {
+		return nil, err
+	}
+	for op, raw := range req {
+		var seconds float64
+		if err := json.Unmarshal(raw, &seconds); err == nil {
+			delays[op] = models.Delay{Kind: models.DelayFixed, Value: int(seconds * 1000)}
+			continue
+		}
+		var d models.Delay
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return nil, err
+		}
+		delays[op] = d
+	}
+	return delays, nil
+}
+
+func delayError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, calculator.ErrDelay), errors.Is(err, calculator.ErrUnknownOperation), errors.Is(err, calculator.ErrDelayMode),
+		errors.Is(err, calculator.ErrDelayGroup):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, calculator.ErrProfileNotFound), errors.Is(err, storage.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, "failed to update")
 }
 
 func (s *serverAPI) GetWorkersInfo(ctx context.Context, in *c.Empty) (*c.GetWorkersInfoResponse, error) {
@@ -99,6 +215,173 @@ func (s *serverAPI) GetWorkersInfo(ctx context.Context, in *c.Empty) (*c.GetWork
 	return &c.GetWorkersInfoResponse{Workers: string(js)}, nil
 }
 
+func (s *serverAPI) AddWorkers(ctx context.Context, in *c.AddWorkersRequest) (*c.GetWorkersInfoResponse, error) {
+	_, err := s.Calc.AddWorkers(in.Pool, int(in.Count))
+	if err != nil {
+		if errors.Is(err, calculator.ErrWorkersCount) {
+			return &c.GetWorkersInfoResponse{}, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, calculator.ErrPoolNotFound) {
+			return &c.GetWorkersInfoResponse{}, status.Error(codes.NotFound, err.Error())
+		}
+		return &c.GetWorkersInfoResponse{}, status.Error(codes.Internal, "failed to add")
+	}
+	return s.GetWorkersInfo(ctx, &c.Empty{})
+}
+
+func (s *serverAPI) RemoveWorker(ctx context.Context, in *c.WorkerRequest) (*c.Empty, error) {
+	return &c.Empty{}, workerError(s.Calc.RemoveWorker(int(in.WorkerId)))
+}
+
+func (s *serverAPI) DrainWorker(ctx context.Context, in *c.WorkerRequest) (*c.Empty, error) {
+	return &c.Empty{}, workerError(s.Calc.DrainWorker(int(in.WorkerId)))
+}
+
+func (s *serverAPI) UnquarantineWorker(ctx context.Context, in *c.WorkerRequest) (*c.Empty, error) {
+	err := s.Calc.Unquarantine(int(in.WorkerId))
+	if err != nil {
+		if errors.Is(err, calculator.ErrNotQuarantined) {
+			return &c.Empty{}, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return &c.Empty{}, status.Error(codes.Internal, "failed to unquarantine worker")
+	}
+	return &c.Empty{}, nil
+}
+
+func (s *serverAPI) GetScalingInfo(ctx context.Context, in *c.Empty) (*c.GetScalingInfoResponse, error) {
+	result, _ := s.Calc.GetScalingInfo()
+	js, err := json.Marshal(result)
+	if err != nil {
+		return &c.GetScalingInfoResponse{}, status.Error(codes.Internal, "failed to read")
+	}
+	return &c.GetScalingInfoResponse{Scaling: string(js)}, nil
+}
+
+func (s *serverAPI) GetSchedulerStats(ctx context.Context, in *c.Empty) (*c.GetSchedulerStatsResponse, error) {
+	result, _ := s.Calc.GetSchedulerStats()
+	js, err := json.Marshal(result)
+	if err != nil {
+		return &c.GetSchedulerStatsResponse{}, status.Error(codes.Internal, "failed to read")
+	}
+	return &c.GetSchedulerStatsResponse{Stats: string(js)}, nil
+}
+
+func (s *serverAPI) GetStalledTasks(ctx context.Context, in *c.Empty) (*c.GetStalledTasksResponse, error) {
+	js, err := json.Marshal(s.Calc.GetStalledTasks())
+	if err != nil {
+		return &c.GetStalledTasksResponse{}, status.Error(codes.Internal, "failed to read")
+	}
+	return &c.GetStalledTasksResponse{Tasks: string(js)}, nil
+}
+
+func (s *serverAPI) GetQueue(ctx context.Context, in *c.Empty) (*c.GetQueueResponse, error) {
+	js, err := json.Marshal(s.Calc.GetQueue())
+	if err != nil {
+		return &c.GetQueueResponse{}, status.Error(codes.Internal, "failed to read")
+	}
+	return &c.GetQueueResponse{Queue: string(js)}, nil
+}
+
+func (s *serverAPI) GetDeadLetters(ctx context.Context, in *c.Empty) (*c.GetDeadLettersResponse, error) {
+	letters, err := s.Calc.GetDeadLetters()
+	if err != nil {
+		return &c.GetDeadLettersResponse{}, status.Error(codes.Internal, "failed to read")
+	}
+	js, err := json.Marshal(letters)
+	if err != nil {
+		return &c.GetDeadLettersResponse{}, status.Error(codes.Internal, "failed to read")
+	}
+	return &c.GetDeadLettersResponse{Letters: string(js)}, nil
+}
+
+func (s *serverAPI) RetryDeadLetter(ctx context.Context, in *c.DeadLetterRequest) (*c.Empty, error) {
+	return &c.Empty{}, deadLetterError(s.Calc.RetryDeadLetter(int(in.Id), int(in.UserId)))
+}
+
+func (s *serverAPI) DiscardDeadLetter(ctx context.Context, in *c.DeadLetterRequest) (*c.Empty, error) {
+	return &c.Empty{}, deadLetterError(s.Calc.DiscardDeadLetter(int(in.Id)))
+}
+
+func deadLetterError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, storage.ErrDeadLetterNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, calculator.ErrTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, calculator.ErrShuttingDown):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if err := quotaError(err); err != nil {
+		return err
+	}
+	return status.Error(codes.Internal, "failed to change dead letter")
+}
+
+func (s *serverAPI) SetQuorum(ctx context.Context, in *c.SetQuorumRequest) (*c.Empty, error) {
+	err := s.Calc.SetQuorum(in.All, in.Operations, int(in.Size))
+	if err != nil {
+		return &c.Empty{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &c.Empty{}, nil
+}
+
+func (s *serverAPI) SetUserWeight(ctx context.Context, in *c.SetUserWeightRequest) (*c.Empty, error) {
+	err := s.Calc.SetUserWeight(int(in.UserId), int(in.Weight))
+	if err != nil {
+		if errors.Is(err, calculator.ErrWeight) {
+			return &c.Empty{}, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return &c.Empty{}, status.Error(codes.NotFound, "user not found")
+		}
+		return &c.Empty{}, status.Error(codes.Internal, "failed to set weight")
+	}
+	return &c.Empty{}, nil
+}
+
+func (s *serverAPI) SetDefaultTimeout(ctx context.Context, in *c.SetDefaultTimeoutRequest) (*c.Empty, error) {
+	d, err := time.ParseDuration(in.MaxDuration)
+	if err != nil {
+		return &c.Empty{}, status.Error(codes.InvalidArgument, "invalid maximum duration")
+	}
+	err = s.Calc.SetDefaultTimeout(d)
+	if err != nil {
+		if errors.Is(err, calculator.ErrDefaultTimeout) {
+			return &c.Empty{}, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return &c.Empty{}, status.Error(codes.Internal, "failed to set timeout")
+	}
+	return &c.Empty{}, nil
+}
+
+func (s *serverAPI) SetUserQuota(ctx context.Context, in *c.SetUserQuotaRequest) (*c.Empty, error) {
+	err := s.Calc.SetUserQuota(int(in.UserId), int(in.Concurrent), int(in.PerHour), int(in.DelayPerDay))
+	if err != nil {
+		if errors.Is(err, calculator.ErrQuota) {
+			return &c.Empty{}, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return &c.Empty{}, status.Error(codes.NotFound, "user not found")
+		}
+		return &c.Empty{}, status.Error(codes.Internal, "failed to set quota")
+	}
+	return &c.Empty{}, nil
+}
+
+func workerError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, calculator.ErrWorkerNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, calculator.ErrLastWorker):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Internal, "failed to stop worker")
+}
+
 func (s *serverAPI) GetAllTasks(ctx context.Context, in *c.GetAllTasksRequest) (*c.GetAllTasksResponse, error) {
 	result, err := s.Calc.GetAllTasks(in.UserId)
 	if err != nil {
@@ -113,7 +396,22 @@ func (s *serverAPI) GetAllTasks(ctx context.Context, in *c.GetAllTasksRequest) (
 }
 
 func (s *serverAPI) AddTask(ctx context.Context, in *c.AddTaskRequest) (*c.AddTaskResponse, error) {
-	task, err := s.Calc.NewTask(int(in.UserId), in.Task)
+	opts := calculator.TaskOptions{Priority: in.Priority}
+	if in.Deadline != "" {
+		deadline, err := time.Parse(time.RFC3339, in.Deadline)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid deadline")
+		}
+		opts.Deadline = deadline
+	}
+	if in.MaxDuration != "" {
+		d, err := time.ParseDuration(in.MaxDuration)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid maximum duration")
+		}
+		opts.MaxDuration = d
+	}
+	task, err := s.Calc.NewTask(int(in.UserId), in.Task, opts)
 	if err != nil {
 		if err == storage.ErrUserNotFound {
 			return nil, status.Error(codes.InvalidArgument, "user not found")
@@ -121,10 +419,25 @@ func (s *serverAPI) AddTask(ctx context.Context, in *c.AddTaskRequest) (*c.AddTa
 		if err.Error() == "выражение недопустимо" {
 			return nil, status.Error(codes.InvalidArgument, "invalid expression")
 		}
+		if errors.Is(err, calculator.ErrPriority) || errors.Is(err, calculator.ErrDeadline) || errors.Is(err, calculator.ErrMaxDuration) || errors.Is(err, calculator.ErrDeadlineTwice) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, calculator.ErrHighPriority) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		if errors.Is(err, calculator.ErrShuttingDown) {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		if err := quotaError(err); err != nil {
+			return nil, err
+		}
 		return nil, status.Error(codes.Internal, "failed to add")
 	}
 	go task.Start()
-	result := map[string]interface{}{"id": task.Id, "expression": task.Expression, "status": "processing"}
+	result := map[string]interface{}{"id": task.Id, "expression": task.Expression, "status": task.Status, "priority": task.Priority}
+	if !task.Deadline.IsZero() {
+		result["deadline"] = task.Deadline.Format("2006-01-02 15:04:05")
+	}
 	js, err := json.Marshal(result)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to read")
@@ -132,6 +445,190 @@ func (s *serverAPI) AddTask(ctx context.Context, in *c.AddTaskRequest) (*c.AddTa
 	return &c.AddTaskResponse{Task: string(js)}, nil
 }
 
+// quotaError returns the ResourceExhausted status for a quota error, with
+// the retry delay if there is one, and nil for other errors.
+func quotaError(err error) error {
+	var quota *calculator.QuotaError
+	if errors.As(err, &quota) {
+		st, _ := status.New(codes.ResourceExhausted, err.Error()).WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(quota.RetryAfter)})
+		return st.Err()
+	}
+	if errors.Is(err, calculator.ErrTaskCost) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return nil
+}
+
+func (s *serverAPI) CancelTask(ctx context.Context, in *c.TaskRequest) (*c.Empty, error) {
+	return &c.Empty{}, taskError(s.Calc.CancelTask(int(in.TaskId), int(in.UserId)))
+}
+
+func (s *serverAPI) PauseTask(ctx context.Context, in *c.TaskRequest) (*c.Empty, error) {
+	return &c.Empty{}, taskError(s.Calc.PauseTask(int(in.TaskId), int(in.UserId)))
+}
+
+func (s *serverAPI) ResumeTask(ctx context.Context, in *c.TaskRequest) (*c.Empty, error) {
+	return &c.Empty{}, taskError(s.Calc.ResumeTask(int(in.TaskId), int(in.UserId)))
+}
+
+func taskError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case err == storage.ErrTaskNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, calculator.ErrTaskNotRunning), errors.Is(err, calculator.ErrTaskNotPaused), errors.Is(err, calculator.ErrTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, calculator.ErrShuttingDown):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if err := quotaError(err); err != nil {
+		return err
+	}
+	return status.Error(codes.Internal, "failed to change task")
+}
+
+func scheduleOptions(in *c.ScheduleRequest) (calculator.ScheduleOptions, error) {
+	opts := calculator.ScheduleOptions{Expression: in.Expression, Priority: in.Priority, Cron: in.Cron, CatchUp: in.CatchUp}
+	if in.RunAt != "" {
+		runAt, err := time.Parse(time.RFC3339, in.RunAt)
+		if err != nil {
+			return opts, status.Error(codes.InvalidArgument, "invalid run time")
+		}
+		opts.RunAt = runAt
+	}
+	return opts, nil
+}
+
+func scheduleResponse(schedule models.Schedule, err error) (*c.ScheduleResponse, error) {
+	if err != nil {
+		return nil, scheduleError(err)
+	}
+	js, err := json.Marshal(schedule)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to read")
+	}
+	return &c.ScheduleResponse{Schedule: string(js)}, nil
+}
+
+func (s *serverAPI) AddSchedule(ctx context.Context, in *c.ScheduleRequest) (*c.ScheduleResponse, error) {
+	opts, err := scheduleOptions(in)
+	if err != nil {
+		return nil, err
+	}
+	return scheduleResponse(s.Calc.AddSchedule(int(in.UserId), opts))
+}
+
+func (s *serverAPI) UpdateSchedule(ctx context.Context, in *c.ScheduleRequest) (*c.ScheduleResponse, error) {
+	opts, err := scheduleOptions(in)
+	if err != nil {
+		return nil, err
+	}
+	return scheduleResponse(s.Calc.UpdateSchedule(int(in.ScheduleId), int(in.UserId), opts))
+}
+
+func (s *serverAPI) GetSchedules(ctx context.Context, in *c.ScheduleRequest) (*c.GetSchedulesResponse, error) {
+	result, err := s.Calc.GetSchedules(int(in.UserId))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to read")
+	}
+	js, err := json.Marshal(result)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to read")
+	}
+	return &c.GetSchedulesResponse{Schedules: string(js)}, nil
+}
+
+func (s *serverAPI) PauseSchedule(ctx context.Context, in *c.ScheduleRequest) (*c.Empty, error) {
+	return &c.Empty{}, scheduleError(s.Calc.PauseSchedule(int(in.ScheduleId), int(in.UserId)))
+}
+
+func (s *serverAPI) ResumeSchedule(ctx context.Context, in *c.ScheduleRequest) (*c.Empty, error) {
+	return &c.Empty{}, scheduleError(s.Calc.ResumeSchedule(int(in.ScheduleId), int(in.UserId)))
+}
+
+func (s *serverAPI) DeleteSchedule(ctx context.Context, in *c.ScheduleRequest) (*c.Empty, error) {
+	return &c.Empty{}, scheduleError(s.Calc.DeleteSchedule(int(in.ScheduleId), int(in.UserId)))
+}
+
+func scheduleError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, storage.ErrScheduleNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, calculator.ErrScheduleNotActive), errors.Is(err, calculator.ErrScheduleNotPaused):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, calculator.ErrHighPriority):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, calculator.ErrPriority), errors.Is(err, calculator.ErrCron), errors.Is(err, calculator.ErrScheduleTime),
+		errors.Is(err, calculator.ErrRunAt), errors.Is(err, calculator.ErrCatchUp), errors.Is(err, calculator.ErrScheduleRecurrence):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case err.Error() == "выражение недопустимо":
+		return status.Error(codes.InvalidArgument, "invalid expression")
+	}
+	return status.Error(codes.Internal, "failed to change schedule")
+}
+
+func (s *serverAPI) RegisterAgent(ctx context.Context, in *c.RegisterAgentRequest) (*c.RegisterAgentResponse, error) {
+	id, err := s.Agents.RegisterAgent(in.Name, int(in.Slots))
+	if err != nil {
+		if errors.Is(err, calculator.ErrAgentName) || errors.Is(err, calculator.ErrAgentSlots) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Internal, "failed to register agent")
+	}
+	return &c.RegisterAgentResponse{AgentId: int64(id)}, nil
+}
+
+func (s *serverAPI) GetSubtask(ctx context.Context, in *c.GetSubtaskRequest) (*c.GetSubtaskResponse, error) {
+	subtask, err := s.Agents.GetSubtask(ctx, int(in.AgentId))
+	if err != nil {
+		return nil, agentError(err)
+	}
+	if subtask == nil {
+		return &c.GetSubtaskResponse{}, nil
+	}
+	return &c.GetSubtaskResponse{
+		Found:             true,
+		SubtaskId:         int64(subtask.Id),
+		TaskId:            int64(subtask.TaskId),
+		Expression:        subtask.Expression,
+		Operation:         subtask.Operation,
+		Delay:             int64(subtask.Delay / time.Second),
+		DelayMs:           subtask.Delay.Milliseconds(),
+		HeartbeatInterval: subtask.HeartbeatInterval.Milliseconds(),
+	}, nil
+}
+
+func (s *serverAPI) Heartbeat(ctx context.Context, in *c.HeartbeatRequest) (*c.HeartbeatResponse, error) {
+	ok, err := s.Agents.Heartbeat(int(in.AgentId), int(in.SubtaskId))
+	if err != nil {
+		return nil, agentError(err)
+	}
+	return &c.HeartbeatResponse{LeaseLost: !ok}, nil
+}
+
+func (s *serverAPI) SendResult(ctx context.Context, in *c.SendResultRequest) (*c.Empty, error) {
+	err := s.Agents.SendResult(int(in.AgentId), int(in.SubtaskId), in.Result, in.Error)
+	if err != nil {
+		return nil, agentError(err)
+	}
+	return &c.Empty{}, nil
+}
+
+func agentError(err error) error {
+	switch {
+	case errors.Is(err, calculator.ErrAgentNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, calculator.ErrLeaseLost):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, "failed to process")
+}
+
 func (s *serverAPI) Login(ctx context.Context, in *c.LoginRequest) (*c.LoginResponse, error) {
 	if in.Name == "" {
 		return nil, status.Error(codes.InvalidArgument, "name is required")
@@ -0,0 +1,51 @@
+package eval
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Knetic/govaluate"
+)
+
+// Stages at which computing a subtask can fail.
+const (
+	StageParse    = "parse"
+	StageEvaluate = "evaluate"
+	StageResult   = "result"
+	StagePanic    = "panic"
+)
+
+// Error is a subtask expression that could not be computed.
+type Error struct {
+	Expression string
+	Stage      string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %q: %s", e.Stage, e.Expression, e.Message)
+}
+
+// Eval computes a single subtask expression such as "2*3" and formats the
+// result the way it is stored in the task. Every failure, a panic included,
+// is returned as an *Error.
+func Eval(value string) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = "", &Error{Expression: value, Stage: StagePanic, Message: fmt.Sprint(r)}
+		}
+	}()
+	expression, err := govaluate.NewEvaluableExpression(value)
+	if err != nil {
+		return "", &Error{Expression: value, Stage: StageParse, Message: err.Error()}
+	}
+	evaluated, err := expression.Evaluate(nil)
+	if err != nil {
+		return "", &Error{Expression: value, Stage: StageEvaluate, Message: err.Error()}
+	}
+	number, ok := evaluated.(float64)
+	if !ok {
+		return "", &Error{Expression: value, Stage: StageResult, Message: fmt.Sprintf("unexpected result %v", evaluated)}
+	}
+	return strconv.FormatFloat(number, 'f', -1, 64), nil
+}
@@ -0,0 +1,260 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/app/websocket"
+	"github.com/apple5343/golangProjectV2/internal/config"
+)
+
+// builtinAgent is the name under which the in-process workers are shown next
+// to the remote agents.
+const builtinAgent = "local"
+
+var (
+	ErrAgentNotFound = errors.New("agent not found")
+	ErrAgentName     = errors.New("agent name is required")
+	ErrAgentSlots    = errors.New("agent slots must be positive")
+)
+
+// RemoteSubtask is what an agent gets to compute.
+type RemoteSubtask struct {
+	Id                int
+	TaskId            int
+	Expression        string
+	Operation         string
+	Delay             time.Duration
+	HeartbeatInterval time.Duration
+}
+
+type agent struct {
+	id         int
+	name       string
+	slots      int
+	registered time.Time
+	lastSeen   time.Time
+	completed  int
+	lastError  string
+}
+
+type agents struct {
+	mu           sync.Mutex
+	list         map[int]*agent
+	pollTimeout  time.Duration
+	offlineAfter time.Duration
+}
+
+func newAgents(cfg config.AgentsConfig) *agents {
+	if cfg.PollTimeout <= 0 {
+		cfg.PollTimeout = 5 * time.Second
+	}
+	if cfg.OfflineAfter <= 0 {
+		cfg.OfflineAfter = 30 * time.Second
+	}
+	return &agents{list: make(map[int]*agent), pollTimeout: cfg.PollTimeout, offlineAfter: cfg.OfflineAfter}
+}
+
+func (c *Calculator) RegisterAgent(name string, slots int) (int, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, ErrAgentName
+	}
+	if slots <= 0 {
+		return 0, ErrAgentSlots
+	}
+	id := c.Worker.newId()
+	c.agents.mu.Lock()
+	c.agents.list[id] = &agent{id: id, name: name, slots: slots, registered: time.Now(), lastSeen: time.Now()}
+	c.agents.mu.Unlock()
+	c.Updates <- *websocket.UpdateWorkerMessage(id, eventWorkerAdded, "", 0, nil)
+	return id, nil
+}
+
+// seen marks the agent as alive and returns it. c.agents.mu must be held.
+func (c *Calculator) seen(agentId int) (*agent, error) {
+	a, ok := c.agents.list[agentId]
+	if !ok {
+		return nil, ErrAgentNotFound
+	}
+	a.lastSeen = time.Now()
+	return a, nil
+}
+
+// onlineAgents returns the ids of the agents seen within offlineAfter.
+func (c *Calculator) onlineAgents() []int {
+	c.agents.mu.Lock()
+	defer c.agents.mu.Unlock()
+	result := []int{}
+	for _, a := range c.agents.list {
+		if time.Since(a.lastSeen) <= c.agents.offlineAfter {
+			result = append(result, a.id)
+		}
+	}
+	return result
+}
+
+// reapAgents forgets the agents that have not been seen for offlineAfter.
+// Their leases expire on their own and an agent that comes back registers
+// again.
+func (c *Calculator) reapAgents() {
+	ticker := time.NewTicker(c.agents.offlineAfter / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopping:
+			return
+		case <-ticker.C:
+		}
+		gone := []int{}
+		c.agents.mu.Lock()
+		for id, a := range c.agents.list {
+			if time.Since(a.lastSeen) > c.agents.offlineAfter {
+				delete(c.agents.list, id)
+				gone = append(gone, id)
+			}
+		}
+		c.agents.mu.Unlock()
+		for _, id := range gone {
+			c.Updates <- *websocket.UpdateWorkerMessage(id, string(stateOffline), "", 0, nil)
+		}
+	}
+}
+
+// GetSubtask waits for a subtask for the agent. It returns nil if nothing
+// was queued during the poll timeout, all slots of the agent are taken, the
+// agent is quarantined or the server is shutting down.
+func (c *Calculator) GetSubtask(ctx context.Context, agentId int) (*RemoteSubtask, error) {
+	c.agents.mu.Lock()
+	a, err := c.seen(agentId)
+	if err != nil {
+		c.agents.mu.Unlock()
+		return nil, err
+	}
+	slots, name := a.slots, a.name
+	c.agents.mu.Unlock()
+	full := len(c.leases.held(agentId)) >= slots
+	if full || c.leases.quorum.isQuarantined(agentId) {
+		return nil, nil
+	}
+	select {
+	case v := <-c.toProcess.ch(sharedPool):
+		if !c.leases.take(c.toProcess, v, agentId) {
+			return nil, nil
+		}
+		delay := sampleDelay(c.delayOf(v))
+		v.taken(agentId, name, delay)
+		return &RemoteSubtask{
+			Id:                v.key,
+			TaskId:            v.taskId,
+			Expression:        v.substack.value,
+			Operation:         v.substack.op,
+			Delay:             delay,
+			HeartbeatInterval: c.leases.heartbeatInterval(),
+		}, nil
+	case <-time.After(c.agents.pollTimeout):
+		return nil, nil
+	case <-c.stopping:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Heartbeat keeps the lease of the subtask alive. It returns false if the
+// agent lost the lease and should stop computing the subtask.
+func (c *Calculator) Heartbeat(agentId, subtaskId int) (bool, error) {
+	c.agents.mu.Lock()
+	_, err := c.seen(agentId)
+	c.agents.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+	return c.leases.heartbeat(subtaskId, agentId), nil
+}
+
+// SendResult accepts the result of a subtask from the agent. If the agent
+// could not compute it, the subtask is queued again. Results for leases the
+// agent no longer holds are ignored.
+func (c *Calculator) SendResult(agentId, subtaskId int, result, errMsg string) error {
+	c.agents.mu.Lock()
+	_, err := c.seen(agentId)
+	c.agents.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if errMsg != "" {
+		if !c.fail(subtaskId, agentId, errMsg) {
+			return ErrLeaseLost
+		}
+		c.agentDone(agentId, errMsg)
+		return nil
+	}
+	if !c.report(subtaskId, agentId, result) {
+		c.agentDone(agentId, ErrLeaseLost.Error())
+		return ErrLeaseLost
+	}
+	c.agentDone(agentId, "")
+	return nil
+}
+
+// agentDone updates the statistics of the agent after it reported a subtask.
+func (c *Calculator) agentDone(agentId int, errMsg string) {
+	c.agents.mu.Lock()
+	defer c.agents.mu.Unlock()
+	a, ok := c.agents.list[agentId]
+	if !ok {
+		return
+	}
+	if errMsg == "" {
+		a.completed++
+	} else {
+		a.lastError = errMsg
+	}
+}
+
+func (c *Calculator) agentsInfo() []map[string]interface{} {
+	c.agents.mu.Lock()
+	defer c.agents.mu.Unlock()
+	result := []map[string]interface{}{}
+	for _, a := range c.agents.list {
+		status := stateIdle
+		if time.Since(a.lastSeen) > c.agents.offlineAfter {
+			status = stateOffline
+		}
+		held := c.leases.held(a.id)
+		if len(held) > 0 && status != stateOffline {
+			status = stateBusy
+		}
+		if c.leases.quorum.isQuarantined(a.id) {
+			status = stateQuarantined
+		}
+		running := []map[string]interface{}{}
+		for _, v := range held {
+			running = append(running, map[string]interface{}{"expression": v.substack.value, "expressionId": v.taskId})
+		}
+		result = append(result, map[string]interface{}{
+			"id":           a.id,
+			"agent":        a.name,
+			"remote":       true,
+			"slots":        a.slots,
+			"status":       status,
+			"expression":   "",
+			"expressionId": 0,
+			"running":      running,
+			"lastSeen":     a.lastSeen.Format("2006-01-02 15:04:05"),
+			"strikes":      c.leases.quorum.strikesOf(a.id),
+			"stats": websocket.WorkerStats{
+				Started:   a.registered.Format("2006-01-02 15:04:05"),
+				Completed: a.completed,
+				LastError: a.lastError,
+			},
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i]["id"].(int) < result[j]["id"].(int) })
+	return result
+}
@@ -0,0 +1,147 @@
+package calculator
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/app/websocket"
+	"github.com/apple5343/golangProjectV2/internal/config"
+)
+
+const (
+	scaleUp   = "up"
+	scaleDown = "down"
+
+	decisionsLimit = 20
+)
+
+type scalingDecision struct {
+	Time    string `json:"time"`
+	Action  string `json:"action"`
+	Workers int    `json:"workers"`
+	Depth   int    `json:"depth"`
+	Wait    string `json:"wait"`
+	Reason  string `json:"reason"`
+}
+
+type autoscaler struct {
+	mu        sync.Mutex
+	cfg       config.AutoscaleConfig
+	lastScale time.Time
+	decisions []scalingDecision
+}
+
+func newAutoscaler(cfg config.AutoscaleConfig) *autoscaler {
+	if cfg.Min <= 0 {
+		cfg.Min = 1
+	}
+	if cfg.Max < cfg.Min {
+		cfg.Max = cfg.Min
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = 10 * time.Second
+	}
+	if cfg.QueuePerWorker <= 0 {
+		cfg.QueuePerWorker = 2
+	}
+	return &autoscaler{cfg: cfg}
+}
+
+func (c *Calculator) autoscale() {
+	ticker := time.NewTicker(c.scaler.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopping:
+			return
+		case <-ticker.C:
+		}
+		c.scaleStep()
+	}
+}
+
+// scaleStep adds workers while subtasks pile up or wait for too long and
+// drains idle ones once the queue is empty. Nothing happens during the
+// cooldown that follows the previous decision.
+func (c *Calculator) scaleStep() {
+	a := c.scaler
+	a.mu.Lock()
+	cooling := time.Since(a.lastScale) < a.cfg.Cooldown
+	a.mu.Unlock()
+	if cooling {
+		return
+	}
+	depth, wait := c.toProcess.stats(sharedPool)
+	active, idle := c.Worker.counts(sharedPool)
+	switch {
+	case depth > 0 && active < a.cfg.Max && (wait >= a.cfg.MaxWait || depth >= a.cfg.QueuePerWorker):
+		add := (depth + a.cfg.QueuePerWorker - 1) / a.cfg.QueuePerWorker
+		if active+add > a.cfg.Max {
+			add = a.cfg.Max - active
+		}
+		for i := 0; i < add; i++ {
+			c.Worker.AddWorker(sharedPool)
+		}
+		reason := fmt.Sprintf("%d subtasks waiting", depth)
+		if wait >= a.cfg.MaxWait {
+			reason = fmt.Sprintf("oldest subtask waiting for %s", wait.Round(time.Second))
+		}
+		c.recordDecision(scaleUp, active+add, depth, wait, reason)
+	case depth == 0 && idle > 0 && active > a.cfg.Min:
+		id := c.Worker.idleWorker(sharedPool)
+		if id == 0 || c.Worker.DrainWorker(id) != nil {
+			return
+		}
+		c.recordDecision(scaleDown, active-1, depth, wait, fmt.Sprintf("queue is empty, worker %d is idle", id))
+	}
+}
+
+func (c *Calculator) recordDecision(action string, workers, depth int, wait time.Duration, reason string) {
+	d := scalingDecision{
+		Time:    time.Now().Format("2006-01-02 15:04:05"),
+		Action:  action,
+		Workers: workers,
+		Depth:   depth,
+		Wait:    wait.Round(time.Millisecond).String(),
+		Reason:  reason,
+	}
+	log.Printf("autoscale %s to %d workers: %s", action, workers, reason)
+	a := c.scaler
+	a.mu.Lock()
+	a.lastScale = time.Now()
+	a.decisions = append(a.decisions, d)
+	if len(a.decisions) > decisionsLimit {
+		a.decisions = a.decisions[len(a.decisions)-decisionsLimit:]
+	}
+	a.mu.Unlock()
+	c.Updates <- *websocket.UpdateScalingMessage(d.Time, d.Action, d.Workers, d.Depth, d.Wait, d.Reason)
+}
+
+func (c *Calculator) GetScalingInfo() (map[string]interface{}, error) {
+	depth, wait := c.toProcess.stats(sharedPool)
+	active, idle := c.Worker.counts(sharedPool)
+	a := c.scaler
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	decisions := make([]scalingDecision, len(a.decisions))
+	copy(decisions, a.decisions)
+	return map[string]interface{}{
+		"enabled":    a.cfg.Enabled,
+		"min":        a.cfg.Min,
+		"max":        a.cfg.Max,
+		"cooldown":   a.cfg.Cooldown.String(),
+		"workers":    active,
+		"idle":       idle,
+		"queueDepth": depth,
+		"oldestWait": wait.Round(time.Millisecond).String(),
+		"decisions":  decisions,
+	}, nil
+}
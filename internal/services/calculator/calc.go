@@ -3,38 +3,45 @@ package calculator
 import (
 	"encoding/json"
 	"fmt"
-	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/Knetic/govaluate"
 	"github.com/apple5343/golangProjectV2/internal/app/websocket"
 	"github.com/apple5343/golangProjectV2/internal/config"
+	"github.com/apple5343/golangProjectV2/internal/domain/models"
 	storage "github.com/apple5343/golangProjectV2/internal/storage/sqlite"
 	"golang.org/x/exp/slices"
 )
 
 type Calculator struct {
-	toProcess   chan *Subtask
+	toProcess   *queue
 	db          storage.SqlDB
 	Worker      Worker
 	Updates     chan websocket.Event
 	UpdatesTask chan TaskUpdate
-}
-
-type Worker struct {
-	mu        sync.Mutex
-	list      []map[string]interface{}
-	Delays    map[string]int
-	toProcess chan *Subtask
-	Updates   chan websocket.Event
+	scaler      *autoscaler
+	agents      *agents
+	leases      *leases
+	speculator  *speculator
+	quotas      *quotas
+	tasks       *tasks
+	deadlines   *deadlines
+	watchdog    *watchdog
+	retries     *retries
+	delays      *delays
+	inspector   *inspector
+	stopping    chan struct{}
+	stopOnce    sync.Once
+	// reporting counts the results being reported, see Shutdown.
+	reporting atomic.Int32
 }
 
 type Task struct {
 	db         storage.SqlDB
 	subtask    *Spliter
-	toProcess  chan *Subtask
+	toProcess  *queue
 	Id         int
 	Status     string
 	Expression string
@@ -42,14 +49,78 @@ type Task struct {
 	Created    time.Time
 	LastPing   time.Time
 	UserID     int
-	UpdateCh   chan TaskUpdate
+	Priority   string
+	Deadline   time.Time
+	// DelayVersion is the version of the delays the task started with.
+	DelayVersion int
+	UpdateCh     chan TaskUpdate
+	tasks        *tasks
+	cancelled    chan struct{}
+	// paused is guarded by tasks.mu.
+	paused bool
+	// round is the expression the current round is split from. restored
+	// holds the results of a round journaled before a restart.
+	round    string
+	restored map[int]string
+	// started is set once a worker has taken a subtask of the task.
+	started atomic.Bool
+	// current are the subtasks of the round being computed, guarded by
+	// tasks.mu.
+	current []*Subtask
 }
 
+// Subtask is one operation of a task waiting for or being computed by a
+// worker. Speculative and quorum copies are shallow copies of it: they share
+// the symbol, the wait group, finished, cancelled and failures with the
+// original, so whichever copy completes or abandons the subtask does it once
+// for all of them. The key is shared too, while avoid, ballot and timing
+// belong to each copy.
 type Subtask struct {
+	key      int
 	substack *Symbol
 	pingCh   chan int
 	wg       *sync.WaitGroup
 	taskId   int
+	userId   int
+	priority string
+	// avoid is the holder a speculative copy must not be given to.
+	avoid int
+	// ballot is the quorum ballot the copy was made for, 0 if none.
+	ballot int
+	// delayVersion is the delay version of the task.
+	delayVersion int
+	finished     *atomic.Bool
+	cancelled    <-chan struct{}
+	// failures counts the failed attempts of all copies of the subtask.
+	failures *atomic.Int32
+	// unsaved is tasks.unsaved.
+	unsaved *atomic.Int32
+	// start is called with the worker or agent that takes the subtask.
+	start func(holder int)
+	// timing is who computes this copy of the subtask and since when.
+	timing models.StepTiming
+}
+
+// taken records that the worker or the agent took the subtask with the
+// operation delay in effect.
+func (s *Subtask) taken(holder int, agent string, delay time.Duration) {
+	s.timing.WorkerID, s.timing.Agent, s.timing.Delay = holder, agent, delay
+	s.timing.Started = time.Now()
+	s.start(holder)
+}
+
+// complete stores the result and reports the subtask to its task. Only the
+// first copy of a subtask to finish is reported.
+func (s *Subtask) complete(result string) {
+	if s.finished.Swap(true) {
+		return
+	}
+	s.substack.result = result
+	s.substack.timing = s.timing
+	s.substack.timing.Finished = time.Now()
+	s.unsaved.Add(1)
+	s.pingCh <- s.substack.id
+	s.wg.Done()
 }
 
 type TaskUpdate struct {
@@ -58,6 +129,8 @@ type TaskUpdate struct {
 	LastPing string
 	IsDone   int
 	Result   string
+	// Status is empty if the status of the task did not change.
+	Status string
 }
 
 func (c *Calculator) listenTasksUpdate(ch <-chan TaskUpdate) {
@@ -67,127 +140,144 @@ func (c *Calculator) listenTasksUpdate(ch <-chan TaskUpdate) {
 			if !ok {
 				return
 			}
-			update := websocket.UpdateTaskMessage(task.UserId, task.TaskId, task.IsDone, task.LastPing, task.Result)
+			update := websocket.UpdateTaskMessage(task.UserId, task.TaskId, task.IsDone, task.LastPing, task.Result, task.Status)
 			c.Updates <- *update
 		}
 	}
 }
 
 func NewCalculator(cfg *config.Config, db storage.SqlDB, ch chan websocket.Event) (*Calculator, error) {
-	toProcess := make(chan *Subtask)
-	list := []map[string]interface{}{}
-	calculator := &Calculator{toProcess: toProcess, db: db, Updates: ch}
-	for i := 0; i < 4; i++ {
-		killCh := make(chan int)
-		list = append(list, map[string]interface{}{"id": i + 1, "kill": killCh, "status": "in waiting", "expression": "", "expressionId": 0})
-		go calculator.Worker.worker(toProcess, i+1, killCh)
+	toProcess, err := newQueue(cfg.Workers.Pools)
+	if err != nil {
+		return nil, err
 	}
+	calculator := &Calculator{toProcess: toProcess, db: db, Updates: ch, scaler: newAutoscaler(cfg.Workers.Autoscale), agents: newAgents(cfg.Agents), leases: newLeases(cfg.Leases, newQuorum(cfg.Quorum)), speculator: newSpeculator(cfg.Speculation), quotas: newQuotas(cfg.Quotas), tasks: newTasks(), deadlines: newDeadlines(cfg.Tasks), watchdog: newWatchdog(cfg.Watchdog), retries: newRetries(cfg.Retries), inspector: newInspector(cfg.Inspector), stopping: make(chan struct{})}
 	tasksCh := make(chan TaskUpdate)
 	calculator.UpdatesTask = tasksCh
 	go calculator.listenTasksUpdate(tasksCh)
-	delays, err := db.GetDelays()
+	calculator.delays, err = newDelays(db)
 	if err != nil {
 		return calculator, err
 	}
-	calculator.Worker.Updates = ch
-	calculator.Worker.Delays = delays
-	calculator.Worker.list = list
-	calculator.Worker.toProcess = toProcess
-	return calculator, nil
-}
-
-func (w *Worker) ChangeStatus(id int, newStatus string) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	for _, k := range w.list {
-		if k["id"] == id {
-			k["status"] = newStatus
-			return
-		}
+	weights, err := db.GetUserWeights()
+	if err != nil {
+		return calculator, err
 	}
-}
-
-func (w *Worker) ChangeExpression(id int, expression string, expressionId int) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	for _, k := range w.list {
-		if k["id"] == id {
-			k["expression"] = expression
-			k["expressionId"] = expressionId
-			return
+	for k, v := range weights {
+		toProcess.setWeight(k, v)
+	}
+	maxDuration, err := db.GetSetting(settingMaxDuration)
+	if err != nil {
+		return calculator, err
+	}
+	if maxDuration != "" {
+		if calculator.deadlines.maxDuration, err = time.ParseDuration(maxDuration); err != nil {
+			return calculator, err
 		}
 	}
-}
-
-func (w *Worker) GetWorkersInfo() []map[string]interface{} {
-	result := []map[string]interface{}{}
-	for _, v := range w.list {
-		result = append(result, map[string]interface{}{"id": v["id"], "status": v["status"], "expression": v["expression"], "expressionId": v["expressionId"]})
+	calculator.Worker.Updates = ch
+	calculator.Worker.delays = calculator.delays
+	calculator.Worker.toProcess = toProcess
+	calculator.Worker.leases = calculator.leases
+	calculator.Worker.report = calculator.report
+	calculator.Worker.fail = calculator.fail
+	calculator.leases.skipped = calculator.skipped
+	go calculator.reapLeases()
+	go calculator.reapAgents()
+	go calculator.enforceDeadlines()
+	go calculator.runSchedules()
+	go calculator.watch()
+	go calculator.streamQueue()
+	count := cfg.Workers.Count
+	if count <= 0 {
+		count = defaultWorkersCount
 	}
-	return result
-}
-
-func (w *Worker) RemoveWorker(id int) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	workers := []map[string]interface{}{}
-	for _, v := range w.list {
-		if v["id"] != id {
-			workers = append(workers, v)
-		} else {
-			v["kill"].(chan int) <- 0
+	for i := 0; i < count; i++ {
+		calculator.Worker.AddWorker(sharedPool)
+	}
+	for _, v := range cfg.Workers.Pools {
+		count := v.Count
+		if count <= 0 {
+			count = 1
 		}
+		for i := 0; i < count; i++ {
+			calculator.Worker.AddWorker(v.Name)
+		}
+	}
+	if calculator.scaler.cfg.Enabled {
+		go calculator.autoscale()
 	}
-	w.list = workers
-	if len(w.list)-1 == 1 {
-		i := w.list[len(w.list)-1][`id`].(int) + 1
-		killCh := make(chan int)
-		w.list = append(w.list, map[string]interface{}{"id": i, "kill": killCh, "status": "in waiting", "expression": "", "expressionId": 0})
-		go w.worker(w.toProcess, i, killCh)
+	if calculator.speculator.cfg.Enabled {
+		go calculator.speculate()
 	}
+	return calculator, nil
 }
 
-func (w *Worker) worker(in chan *Subtask, id int, killCh <-chan int) {
-	for {
-		select {
-		case v := <-in:
-			w.ChangeStatus(id, "at work")
-			expression, _ := govaluate.NewEvaluableExpression(v.substack.value)
-			w.ChangeExpression(id, v.substack.value, v.taskId)
-			update := websocket.UpdateWorkerMessage(id, "at work", v.substack.value, v.taskId)
-			w.Updates <- *update
-			select {
-			case <-time.After(time.Duration(w.Delays[v.substack.op]) * time.Second):
-				result, _ := expression.Eval(nil)
-				v.substack.result = strconv.FormatFloat(result.(float64), 'f', -1, 64)
-				v.pingCh <- v.substack.id
-				v.wg.Done()
-				w.ChangeStatus(id, "in waiting")
-				update := websocket.UpdateWorkerMessage(id, "in waiting", "", 0)
-				w.Updates <- *update
-				w.ChangeExpression(id, "", 0)
-			case <-killCh:
-				in <- v
-				return
-			}
-		case <-killCh:
-			return
-		}
-	}
+// TaskOptions are the optional parameters of a new task.
+type TaskOptions struct {
+	Priority string
+	// Deadline and MaxDuration limit how long the task may take, at most
+	// one of them may be set.
+	Deadline    time.Time
+	MaxDuration time.Duration
+	// By is who creates the task for the status history, the user if empty.
+	By string
 }
 
-func (c *Calculator) NewTask(userID int, expression string) (*Task, error) {
+// NewTask creates a task with the options. An empty priority means normal,
+// high priority is only available to admins. The task is refused if it would
+// exceed a quota of the user or the server is shutting down.
+func (c *Calculator) NewTask(userID int, expression string, opts TaskOptions) (*Task, error) {
+	if c.isStopping() {
+		return nil, ErrShuttingDown
+	}
 	expression = strings.ReplaceAll(expression, " ", "")
 	if !IsValidExpression(expression) {
 		return nil, fmt.Errorf("выражение недопустимо")
 	}
+	priority := opts.Priority
+	if priority == "" {
+		priority = priorityNormal
+	}
+	if _, ok := priorities[priority]; !ok {
+		return nil, ErrPriority
+	}
+	if priority == priorityHigh {
+		admin, err := c.db.IsAdmin(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !admin {
+			return nil, ErrHighPriority
+		}
+	}
+	c.quotas.mu.Lock()
+	defer c.quotas.mu.Unlock()
 	t := time.Now()
-	id, err := c.db.AddTask(expression, userID, t)
+	deadline, err := c.deadline(opts, t)
+	if err != nil {
+		return nil, err
+	}
+	delays := c.delays.current()
+	cost := c.cost(expression, userID, delays.ID)
+	if err := c.checkQuota(userID, cost, t); err != nil {
+		return nil, err
+	}
+	id, err := c.db.AddTask(expression, userID, t, priority, cost, deadline, delays.ID)
 	if err != nil {
 		return nil, err
 	}
+	by := opts.By
+	if by == "" {
+		by = byUser(userID)
+	}
+	if err := c.db.AddStatusHistory(id, models.StatusQueued, by, t); err != nil {
+		return nil, err
+	}
 	spliter := NewSpliter(expression)
-	task := &Task{subtask: spliter, toProcess: c.toProcess, Expression: expression, db: c.db, Created: t, UserID: userID, Id: id, UpdateCh: c.UpdatesTask}
+	task := &Task{subtask: spliter, toProcess: c.toProcess, Expression: expression, db: c.db, Created: t, UserID: userID, Id: id, Status: models.StatusQueued, Priority: priority, Deadline: deadline, DelayVersion: delays.ID,
+		UpdateCh: c.UpdatesTask, tasks: c.tasks, cancelled: make(chan struct{}), round: expression}
+	c.tasks.add(task)
 	return task, nil
 }
 
@@ -195,27 +285,43 @@ func (t *Task) Start() {
 	wg := &sync.WaitGroup{}
 	for !t.subtask.done {
 		t.subtask.Split()
+		processed, err := t.startRound()
+		if err != nil {
+			t.fail(err)
+			return
+		}
 		resultsCh := make(chan int)
+		dispatched := time.Now()
+		current := []*Subtask{}
 		for _, v := range t.subtask.Symbols {
-			if v.expressionType == "calculation" {
-				go func(s *Symbol) {
-					t.toProcess <- &Subtask{substack: s, pingCh: resultsCh, wg: wg, taskId: t.Id}
-				}(v)
+			if v.expressionType == "calculation" && v.result == "" {
 				wg.Add(1)
+				s := &Subtask{substack: v, pingCh: resultsCh, wg: wg, taskId: t.Id, userId: t.UserID, priority: t.Priority, delayVersion: t.DelayVersion, finished: new(atomic.Bool), cancelled: t.cancelled, failures: new(atomic.Int32), unsaved: &t.tasks.unsaved, start: t.markStarted,
+					timing: models.StepTiming{Dispatched: dispatched}}
+				current = append(current, s)
+				t.toProcess.push(s)
 			}
 		}
+		t.tasks.setCurrent(t, current)
 		go func() {
 			wg.Wait()
 			close(resultsCh)
 		}()
-		processed := []int{}
 		for i := range resultsCh {
+			if t.isCancelled() {
+				t.tasks.unsaved.Add(-1)
+				continue
+			}
 			processed = append(processed, i)
+			entry := models.JournalEntry{TaskID: t.Id, SymbolID: i}
+			var timing models.StepTiming
 			updated := ""
 			old := ""
 			lastStep := ""
 			for _, v := range t.subtask.Symbols {
 				if v.id == i {
+					entry.Result = v.result
+					timing = v.timing
 					i = 99999999999
 					old += `<span class=old>` + v.value + `</span>`
 					updated += `<span class=new>` + v.result + `</span>`
@@ -233,19 +339,17 @@ func (t *Task) Start() {
 				}
 			}
 			tm := time.Now()
-			err := t.db.UpdatePing(t.Id, tm)
-			if err != nil {
-				fmt.Println(err)
-			}
-			err = t.db.AddSubtask(old, tm, t.Id, updated)
+			t.tasks.steps.RLock()
+			err := t.db.CompleteSubtask(entry, timing, tm, old, updated, lastStep)
 			if err != nil {
 				fmt.Println(err)
 			}
-			err = t.db.UpdateLastStep(t.Id, lastStep)
-			if err != nil {
-				fmt.Println(err)
-			}
-			t.UpdateCh <- TaskUpdate{t.UserID, t.Id, tm.Format("2006-01-02 15:04:05"), 0, t.Result}
+			t.tasks.steps.RUnlock()
+			t.tasks.unsaved.Add(-1)
+			t.UpdateCh <- TaskUpdate{t.UserID, t.Id, tm.Format("2006-01-02 15:04:05"), 0, t.Result, ""}
+		}
+		if t.isCancelled() {
+			return
 		}
 		newExpression := ""
 		for _, v := range t.subtask.Symbols {
@@ -255,12 +359,66 @@ func (t *Task) Start() {
 				newExpression += v.value
 			}
 		}
+		t.round = newExpression
 		t.subtask.Update(newExpression)
+		if !t.subtask.done && t.tasks.stopIfPaused(t.Id) {
+			return
+		}
+	}
+	if _, ok := t.tasks.remove(t.Id); !ok {
+		return
 	}
 	t.Result = t.subtask.result
-	t.UpdateCh <- TaskUpdate{t.UserID, t.Id, time.Now().Format("2006-01-02 15:04:05"), 1, t.Result}
+	t.tasks.steps.RLock()
 	t.db.SetResult(t.Id, t.Result)
-	t.db.SetStatus(t.Id, "completed")
+	if err := setStatus(t.db, t.Id, models.StatusCompleted, bySystem); err != nil {
+		fmt.Println(err)
+	}
+	t.db.ClearJournal(t.Id)
+	t.tasks.steps.RUnlock()
+	t.UpdateCh <- TaskUpdate{t.UserID, t.Id, time.Now().Format("2006-01-02 15:04:05"), 1, t.Result, models.StatusCompleted}
+}
+
+// startRound journals the subtasks of the round that was just split. A round
+// restored from the journal is not journaled again, its subtasks that were
+// computed before the restart get their results and are returned as
+// processed. The error is that of journaling the round.
+func (t *Task) startRound() ([]int, error) {
+	processed := []int{}
+	if t.restored != nil {
+		for _, v := range t.subtask.Symbols {
+			if result, ok := t.restored[v.id]; ok && v.expressionType == "calculation" {
+				v.result = result
+				processed = append(processed, v.id)
+			}
+		}
+		t.restored = nil
+		return processed, nil
+	}
+	entries := []models.JournalEntry{}
+	for _, v := range t.subtask.Symbols {
+		if v.expressionType == "calculation" {
+			entries = append(entries, models.JournalEntry{TaskID: t.Id, SymbolID: v.id, Round: t.round, Value: v.value})
+		}
+	}
+	t.tasks.steps.RLock()
+	defer t.tasks.steps.RUnlock()
+	return processed, t.db.StartRound(t.Id, entries)
+}
+
+// fail stops the task as failed when its round could not be journaled, as
+// its steps could not be recorded either.
+func (t *Task) fail(err error) {
+	fmt.Println(err)
+	if _, ok := t.tasks.remove(t.Id); !ok {
+		return
+	}
+	t.tasks.steps.RLock()
+	if err := setStatus(t.db, t.Id, models.StatusFailed, bySystem); err != nil {
+		fmt.Println(err)
+	}
+	t.tasks.steps.RUnlock()
+	t.UpdateCh <- TaskUpdate{t.UserID, t.Id, time.Now().Format("2006-01-02 15:04:05"), 0, "", models.StatusFailed}
 }
 
 func (c *Calculator) ContinueCalculations() {
@@ -270,15 +428,13 @@ func (c *Calculator) ContinueCalculations() {
 		return
 	}
 	for _, v := range tasks {
-		lastStep, _ := c.db.GetTaskById(v["id"].(int64), v["userID"].(int64))
-		spliter := NewSpliter(lastStep["lastStep"].(string))
-		t, err := time.Parse("2006-01-02 15:04:05", v["created"].(string))
-		if err != nil {
+		id := int(v["id"].(int64))
+		if err := c.resume(id, int(v["userID"].(int64))); err != nil {
 			fmt.Println(err)
+			if err := setStatus(c.db, id, models.StatusFailed, "restart"); err != nil {
+				fmt.Println(err)
+			}
 		}
-		task := &Task{subtask: spliter, toProcess: c.toProcess, Expression: lastStep["lastStep"].(string), db: c.db, Created: t, Id: int(v["id"].(int64)),
-			UserID: int(v["userID"].(int64)), UpdateCh: c.UpdatesTask}
-		go task.Start()
 	}
 }
 
@@ -287,30 +443,44 @@ func (c *Calculator) GetAllTasks(userID int64) ([]map[string]interface{}, error)
 	return res, err
 }
 
-func (c *Calculator) GetDelays() (map[string]int, error) {
-	res, err := c.db.GetDelays()
-	return res, err
-}
-
 func (c *Calculator) GetTaskById(taskID, userID int64) (string, error) {
 	res, err := c.db.GetTaskById(taskID, userID)
 	if err != nil {
 		return "", err
 	}
+	steps, _ := res["subtasks"].([]map[string]interface{})
+	res["timeline"] = timeline(steps)
 	js, err := json.Marshal(res)
 	return string(js), err
 }
 
 func (c *Calculator) GetWorkersInfo() ([]map[string]interface{}, error) {
-	return c.Worker.GetWorkersInfo(), nil
+	return append(c.Worker.GetWorkersInfo(), c.agentsInfo()...), nil
 }
 
-func (c *Calculator) UpdateDelays(delays map[string]int) error {
-	err := c.db.UpdateDelays(delays)
-	if err == nil {
-		for k, v := range delays {
-			c.Worker.Delays[k] = v
-		}
+// AddWorkers starts workers in the pool. An empty pool name means the shared
+// pool.
+func (c *Calculator) AddWorkers(pool string, count int) ([]int, error) {
+	if count <= 0 {
+		return nil, ErrWorkersCount
+	}
+	if pool == "" {
+		pool = sharedPool
 	}
-	return err
+	if _, ok := c.toProcess.pools[pool]; !ok {
+		return nil, ErrPoolNotFound
+	}
+	ids := []int{}
+	for i := 0; i < count; i++ {
+		ids = append(ids, c.Worker.AddWorker(pool))
+	}
+	return ids, nil
+}
+
+func (c *Calculator) RemoveWorker(id int) error {
+	return c.Worker.RemoveWorker(id)
+}
+
+func (c *Calculator) DrainWorker(id int) error {
+	return c.Worker.DrainWorker(id)
 }
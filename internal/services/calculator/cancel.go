@@ -0,0 +1,119 @@
+package calculator
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/domain/models"
+)
+
+var (
+	ErrTaskNotRunning = errors.New("task is not running")
+	ErrTaskCancelled  = errors.New("task cancelled")
+)
+
+// tasks are the running tasks, so they can be cancelled.
+type tasks struct {
+	mu   sync.Mutex
+	list map[int]*Task
+	// steps is held for reading while a task writes a step and is locked
+	// for good on shutdown.
+	steps sync.RWMutex
+	// unsaved counts the subtask results that were reported to their tasks
+	// but are not written yet.
+	unsaved atomic.Int32
+}
+
+func newTasks() *tasks {
+	return &tasks{list: make(map[int]*Task)}
+}
+
+func (t *tasks) add(task *Task) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.list[task.Id] = task
+}
+
+// remove forgets the task. It returns false if the task was already removed
+// because it was cancelled.
+func (t *tasks) remove(id int) (*Task, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	task, ok := t.list[id]
+	delete(t.list, id)
+	return task, ok
+}
+
+func (t *Task) isCancelled() bool {
+	select {
+	case <-t.cancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Subtask) isCancelled() bool {
+	select {
+	case <-s.cancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// abandon gives up a subtask of a cancelled task, so the task stops waiting
+// for it.
+func (s *Subtask) abandon() {
+	if s.finished.Swap(true) {
+		return
+	}
+	s.wg.Done()
+}
+
+// CancelTask stops the running or paused task of the user. Admins may cancel
+// any task. Queued subtasks of the task are withdrawn and the workers
+// computing the others are interrupted.
+func (c *Calculator) CancelTask(taskID, userID int) error {
+	owner, status, err := c.own(taskID, userID)
+	if err != nil {
+		return err
+	}
+	return c.stop(taskID, owner, status, models.StatusCancelled, byUser(userID))
+}
+
+// stop ends the running or paused task with the new status.
+func (c *Calculator) stop(taskID, owner int, status, newStatus, by string) error {
+	if err := c.interrupt(taskID, status, newStatus, by); err != nil {
+		return err
+	}
+	if err := c.db.ClearJournal(taskID); err != nil {
+		return err
+	}
+	c.UpdatesTask <- TaskUpdate{owner, taskID, time.Now().Format("2006-01-02 15:04:05"), 0, "", newStatus}
+	return nil
+}
+
+// interrupt stops computing the running or paused task and moves it to the
+// new status. The journal of the task is kept.
+func (c *Calculator) interrupt(taskID int, status, newStatus, by string) error {
+	if !canTransition(status, newStatus) {
+		return ErrTaskNotRunning
+	}
+	task, ok := c.tasks.remove(taskID)
+	if !ok && status != models.StatusPaused {
+		return ErrTaskNotRunning
+	}
+	if ok {
+		close(task.cancelled)
+	}
+	for _, s := range c.toProcess.withdraw(taskID) {
+		s.abandon()
+	}
+	for _, s := range c.leases.cancelTask(taskID) {
+		s.abandon()
+	}
+	return setStatus(c.db, taskID, newStatus, by)
+}
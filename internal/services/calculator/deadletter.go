@@ -0,0 +1,148 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/config"
+	"github.com/apple5343/golangProjectV2/internal/domain/models"
+)
+
+const byDeadLetter = "dead letter"
+
+// retries is the policy for subtasks that their holder could not compute:
+// the subtask is queued again after a growing wait until it failed
+// MaxAttempts times, then it becomes a dead letter.
+type retries struct {
+	cfg config.RetriesConfig
+}
+
+func newRetries(cfg config.RetriesConfig) *retries {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.MaxBackoff < cfg.Backoff {
+		cfg.MaxBackoff = max(30*time.Second, cfg.Backoff)
+	}
+	return &retries{cfg: cfg}
+}
+
+// backoff is how long to wait before the next attempt after the failures.
+func (r *retries) backoff(failures int) time.Duration {
+	d := r.cfg.Backoff
+	for i := 1; i < failures && d < r.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.cfg.MaxBackoff)
+}
+
+// fail ends the lease of the holder that could not compute the subtask and
+// handles the failed attempt. It returns false if the holder no longer has
+// the lease.
+func (c *Calculator) fail(key, holder int, reason string) bool {
+	s, ok := c.leases.drop(key, holder)
+	if !ok {
+		return false
+	}
+	c.reassign(s, fmt.Sprintf("worker %d failed: %s", holder, reason))
+	return true
+}
+
+// reassign handles a failed attempt at the subtask: an evaluation error, a
+// crashed agent or an expired lease. The subtask is queued again after the
+// backoff or becomes a dead letter. Every attempt is recorded in the step
+// history.
+func (c *Calculator) reassign(s *Subtask, reason string) {
+	if s.finished.Load() {
+		return
+	}
+	failures := int(s.failures.Add(1))
+	if failures < c.retries.cfg.MaxAttempts {
+		wait := c.retries.backoff(failures)
+		c.note(s, fmt.Sprintf("%s, attempt %d of %d failed, subtask reassigned in %s", reason, failures, c.retries.cfg.MaxAttempts, wait))
+		time.AfterFunc(wait, func() {
+			c.toProcess.push(s)
+		})
+		return
+	}
+	c.note(s, fmt.Sprintf("%s, attempt %d of %d failed, subtask moved to the dead letters", reason, failures, c.retries.cfg.MaxAttempts))
+	c.deadLetter(s, reason, failures)
+}
+
+// deadLetter fails the task of the subtask and stores the subtask as a dead
+// letter. The journal of the task is kept, so a retry continues the round.
+func (c *Calculator) deadLetter(s *Subtask, reason string, failures int) {
+	defer s.abandon()
+	owner, status, err := c.db.GetTaskOwner(s.taskId)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := c.interrupt(s.taskId, status, models.StatusFailed, byDeadLetter); err != nil {
+		// Another subtask of the task is a dead letter already, or the
+		// task was stopped.
+		if !errors.Is(err, ErrTaskNotRunning) && !errors.Is(err, ErrTransition) {
+			fmt.Println(err)
+		}
+		return
+	}
+	now := time.Now().Format("2006-01-02 15:04:05")
+	letter := models.DeadLetter{TaskID: s.taskId, UserID: owner, Value: s.substack.value, Operation: s.substack.op, Attempts: failures, Error: reason, Created: now}
+	if _, err := c.db.AddDeadLetter(letter); err != nil {
+		fmt.Println(err)
+	}
+	c.UpdatesTask <- TaskUpdate{owner, s.taskId, now, 0, "", models.StatusFailed}
+}
+
+func (c *Calculator) GetDeadLetters() ([]models.DeadLetter, error) {
+	return c.db.GetDeadLetters()
+}
+
+// RetryDeadLetter runs the failed task of the dead letter again from its
+// journal. The subtasks get MaxAttempts new attempts.
+func (c *Calculator) RetryDeadLetter(id, userID int) error {
+	if c.isStopping() {
+		return ErrShuttingDown
+	}
+	letter, err := c.db.GetDeadLetter(id)
+	if err != nil {
+		return err
+	}
+	c.quotas.mu.Lock()
+	defer c.quotas.mu.Unlock()
+	if err := c.checkRunning(letter.UserID); err != nil {
+		return err
+	}
+	if err := setStatus(c.db, letter.TaskID, models.StatusProcessing, byUser(userID), models.StatusFailed); err != nil {
+		return err
+	}
+	// The letter is kept until the task runs again, so a failed retry can
+	// be retried.
+	if err := c.resume(letter.TaskID, letter.UserID); err != nil {
+		if err := setStatus(c.db, letter.TaskID, models.StatusFailed, bySystem); err != nil {
+			fmt.Println(err)
+		}
+		return err
+	}
+	if err := c.db.DeleteDeadLetter(id); err != nil {
+		fmt.Println(err)
+	}
+	c.UpdatesTask <- TaskUpdate{letter.UserID, letter.TaskID, time.Now().Format("2006-01-02 15:04:05"), 0, "", models.StatusProcessing}
+	return nil
+}
+
+// DiscardDeadLetter forgets the dead letter, its task stays failed.
+func (c *Calculator) DiscardDeadLetter(id int) error {
+	letter, err := c.db.GetDeadLetter(id)
+	if err != nil {
+		return err
+	}
+	if err := c.db.DeleteDeadLetter(id); err != nil {
+		return err
+	}
+	return c.db.ClearJournal(letter.TaskID)
+}
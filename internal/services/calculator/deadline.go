@@ -0,0 +1,107 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/config"
+	"github.com/apple5343/golangProjectV2/internal/domain/models"
+)
+
+const (
+	// deadlineInterval is how often overdue tasks are looked for.
+	deadlineInterval = time.Second
+
+	settingMaxDuration = "max_duration"
+)
+
+var (
+	ErrDeadline       = errors.New("deadline must be in the future")
+	ErrMaxDuration    = errors.New("maximum duration must be positive")
+	ErrDeadlineTwice  = errors.New("either a deadline or a maximum duration can be set")
+	ErrDefaultTimeout = errors.New("default maximum duration must not be negative")
+)
+
+// deadlines holds the maximum duration of tasks that were submitted without
+// a deadline. Zero means no limit.
+type deadlines struct {
+	mu          sync.Mutex
+	maxDuration time.Duration
+}
+
+func newDeadlines(cfg config.TasksConfig) *deadlines {
+	return &deadlines{maxDuration: cfg.MaxDuration}
+}
+
+func (d *deadlines) get() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.maxDuration
+}
+
+// deadline returns when the task created at the time must be done, or the
+// zero time if it has no deadline.
+func (c *Calculator) deadline(opts TaskOptions, created time.Time) (time.Time, error) {
+	switch {
+	case !opts.Deadline.IsZero() && opts.MaxDuration != 0:
+		return time.Time{}, ErrDeadlineTwice
+	case !opts.Deadline.IsZero():
+		if !opts.Deadline.After(created) {
+			return time.Time{}, ErrDeadline
+		}
+		return opts.Deadline, nil
+	case opts.MaxDuration < 0:
+		return time.Time{}, ErrMaxDuration
+	case opts.MaxDuration > 0:
+		return created.Add(opts.MaxDuration), nil
+	}
+	if d := c.deadlines.get(); d > 0 {
+		return created.Add(d), nil
+	}
+	return time.Time{}, nil
+}
+
+// SetDefaultTimeout sets the maximum duration of tasks submitted without a
+// deadline. Zero removes the limit.
+func (c *Calculator) SetDefaultTimeout(d time.Duration) error {
+	if d < 0 {
+		return ErrDefaultTimeout
+	}
+	if err := c.db.SetSetting(settingMaxDuration, d.String()); err != nil {
+		return err
+	}
+	c.deadlines.mu.Lock()
+	defer c.deadlines.mu.Unlock()
+	c.deadlines.maxDuration = d
+	return nil
+}
+
+// enforceDeadlines stops the running and paused tasks whose deadline has
+// passed. Deadlines are stored with the tasks, so they apply after a restart.
+func (c *Calculator) enforceDeadlines() {
+	ticker := time.NewTicker(deadlineInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopping:
+			return
+		case <-ticker.C:
+		}
+		overdue, err := c.db.GetOverdueTasks(time.Now())
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		for _, v := range overdue {
+			if err := c.stop(v.ID, v.UserID, v.Status, models.StatusTimedOut, "deadline"); err != nil {
+				continue
+			}
+			message := fmt.Sprintf("deadline %s passed, stopped at %s", v.Deadline, v.LastStep)
+			if err := c.db.AddStepNote(v.ID, v.LastStep, message, time.Now()); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/domain/models"
+	storage "github.com/apple5343/golangProjectV2/internal/storage/sqlite"
+)
+
+const settingDelayMode = "delay_mode"
+
+// Delay modes decide which delays the subtasks of running tasks take.
+const (
+	// DelayModeLive gives every subtask the latest delays.
+	DelayModeLive = "live"
+	// DelayModeSnapshot keeps the delays a task started with until it ends.
+	DelayModeSnapshot = "snapshot"
+)
+
+var (
+	ErrDelay           = errors.New("invalid delay")
+	ErrProfileNotFound = errors.New("delay profile not found")
+	ErrDelayMode       = errors.New("delay mode must be live or snapshot")
+)
+
+// checkDelay returns an error if the distribution cannot be drawn from.
+func checkDelay(d models.Delay) error {
+	if d.Value < 0 || d.Min < 0 || d.Max < 0 || d.Mean < 0 || d.StdDev < 0 {
+		return fmt.Errorf("%w: values must not be negative", ErrDelay)
+	}
+	switch d.Kind {
+	case models.DelayFixed:
+	case models.DelayUniform:
+		if d.Max < d.Min {
+			return fmt.Errorf("%w: max is less than min", ErrDelay)
+		}
+	case models.DelayNormal, models.DelayExponential:
+		if d.Mean == 0 {
+			return fmt.Errorf("%w: %s delay needs a mean", ErrDelay, d.Kind)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrDelay, d.Kind)
+	}
+	return nil
+}
+
+// checkDelays returns an error if a delay is not of a known operation or
+// cannot be drawn from.
+func checkDelays(delays map[string]models.Delay) error {
+	for op, d := range delays {
+		if !knownOperation(op) {
+			return fmt.Errorf("%w: %s", ErrUnknownOperation, op)
+		}
+		if err := checkDelay(d); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return nil
+}
+
+// sampleDelay draws a delay from the distribution.
+func sampleDelay(d models.Delay) time.Duration {
+	ms := 0.0
+	switch d.Kind {
+	case models.DelayFixed:
+		ms = float64(d.Value)
+	case models.DelayUniform:
+		ms = float64(d.Min) + rand.Float64()*float64(d.Max-d.Min)
+	case models.DelayNormal:
+		ms = math.Max(float64(d.Min), float64(d.Mean)+rand.NormFloat64()*float64(d.StdDev))
+	case models.DelayExponential:
+		ms = math.Max(float64(d.Min), rand.ExpFloat64()*float64(d.Mean))
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// meanDelay is the delay an operation takes on average, the scheduler plans
+// with it.
+func meanDelay(d models.Delay) time.Duration {
+	ms := 0
+	switch d.Kind {
+	case models.DelayFixed:
+		ms = d.Value
+	case models.DelayUniform:
+		ms = (d.Min + d.Max) / 2
+	case models.DelayNormal, models.DelayExponential:
+		ms = max(d.Mean, d.Min)
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// delays are the delay versions tasks run with. A version is never changed
+// once it is created, a change of the delays adds a version.
+type delays struct {
+	mu       sync.RWMutex
+	db       storage.SqlDB
+	latest   models.DelayVersion
+	versions map[int]map[string]models.Delay
+	mode     string
+	// overrides are replaced as a whole when they change.
+	overrides models.DelayOverrides
+}
+
+// newDelays loads the latest delay version and the delay mode. A database
+// without versions gets its current delays as the first version.
+func newDelays(db storage.SqlDB) (*delays, error) {
+	latest, err := db.GetLatestDelayVersion()
+	if errors.Is(err, storage.ErrDelayVersionNotFound) {
+		current, err := db.GetDelays()
+		if err != nil {
+			return nil, err
+		}
+		latest, err = db.UpdateDelays(current, "", bySystem, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	mode, err := db.GetSetting(settingDelayMode)
+	if err != nil {
+		return nil, err
+	}
+	if mode == "" {
+		mode = DelayModeLive
+	}
+	overrides, err := db.GetDelayOverrides()
+	if err != nil {
+		return nil, err
+	}
+	return &delays{db: db, latest: latest, versions: map[int]map[string]models.Delay{latest.ID: latest.Delays}, mode: mode, overrides: overrides}, nil
+}
+
+// current returns the latest version.
+func (d *delays) current() models.DelayVersion {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.latest
+}
+
+// of returns the delay of the operation for a subtask of a task that started
+// with the version. In live mode, and for tasks started before delays had
+// versions, that is the latest delay.
+func (d *delays) of(version int, op string) models.Delay {
+	d.mu.RLock()
+	delays, ok := d.versions[version]
+	if d.mode == DelayModeLive || version == 0 {
+		delays, ok = d.latest.Delays, true
+	}
+	d.mu.RUnlock()
+	if ok {
+		return delays[op]
+	}
+	// A task resumed after a restart started with an older version.
+	v, err := d.db.GetDelayVersion(version)
+	if err != nil {
+		fmt.Println(err)
+		return d.of(0, op)
+	}
+	d.mu.Lock()
+	d.versions[version] = v.Delays
+	d.mu.Unlock()
+	return v.Delays[op]
+}
+
+// GetDelays returns the delays in effect, their version, the name of the
+// profile they were taken from, the delay mode and the saved profiles.
+func (c *Calculator) GetDelays() (map[string]interface{}, error) {
+	profiles, err := c.db.GetDelayProfiles()
+	if err != nil {
+		return nil, err
+	}
+	current := c.delays.current()
+	c.delays.mu.RLock()
+	mode := c.delays.mode
+	c.delays.mu.RUnlock()
+	return map[string]interface{}{
+		"delays":   current.Delays,
+		"version":  current.ID,
+		"profile":  current.Profile,
+		"mode":     mode,
+		"profiles": profiles,
+	}, nil
+}
+
+// GetDelayHistory returns every delay version, the latest first.
+func (c *Calculator) GetDelayHistory() ([]models.DelayVersion, error) {
+	return c.db.GetDelayVersions()
+}
+
+// UpdateDelays changes the delays of the operations, operations left out
+// keep their current delay. With a profile name the delays are saved as that
+// profile instead. A profile name without delays switches to the profile.
+func (c *Calculator) UpdateDelays(delays map[string]models.Delay, profile string, userID int) error {
+	if err := checkDelays(delays); err != nil {
+		return err
+	}
+	by := byUser(userID)
+	profile = strings.TrimSpace(profile)
+	if profile == "" {
+		return c.setDelays(delays, "", by)
+	}
+	if len(delays) == 0 {
+		profiles, err := c.db.GetDelayProfiles()
+		if err != nil {
+			return err
+		}
+		saved, ok := profiles[profile]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrProfileNotFound, profile)
+		}
+		return c.setDelays(saved, profile, by)
+	}
+	current := c.delays.current()
+	merged := make(map[string]models.Delay)
+	for op, d := range current.Delays {
+		merged[op] = d
+	}
+	for op, d := range delays {
+		merged[op] = d
+	}
+	if err := c.db.SaveDelayProfile(profile, merged); err != nil {
+		return err
+	}
+	if profile == current.Profile {
+		return c.setDelays(merged, profile, by)
+	}
+	return nil
+}
+
+// setDelays creates a version from the current delays changed by the
+// given ones and puts it in effect.
+func (c *Calculator) setDelays(changed map[string]models.Delay, profile, by string) error {
+	c.delays.mu.Lock()
+	defer c.delays.mu.Unlock()
+	delays := make(map[string]models.Delay)
+	for op, d := range c.delays.latest.Delays {
+		delays[op] = d
+	}
+	for op, d := range changed {
+		delays[op] = d
+	}
+	version, err := c.db.UpdateDelays(delays, profile, by, time.Now())
+	if err != nil {
+		return err
+	}
+	c.delays.latest = version
+	c.delays.versions[version.ID] = version.Delays
+	return nil
+}
+
+// SetDelayMode decides whether running tasks take the latest delays or keep
+// the delays they started with.
+func (c *Calculator) SetDelayMode(mode string) error {
+	if mode != DelayModeLive && mode != DelayModeSnapshot {
+		return ErrDelayMode
+	}
+	c.delays.mu.Lock()
+	defer c.delays.mu.Unlock()
+	if err := c.db.SetSetting(settingDelayMode, mode); err != nil {
+		return err
+	}
+	c.delays.mode = mode
+	return nil
+}
@@ -0,0 +1,94 @@
+package calculator
+
+import (
+	"sort"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/app/websocket"
+	"github.com/apple5343/golangProjectV2/internal/config"
+)
+
+// inspector shows the admins the subtasks waiting in the queue.
+type inspector struct {
+	cfg config.InspectorConfig
+}
+
+func newInspector(cfg config.InspectorConfig) *inspector {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 2 * time.Second
+	}
+	if cfg.MaxItems <= 0 {
+		cfg.MaxItems = 100
+	}
+	return &inspector{cfg: cfg}
+}
+
+// snapshot returns the state of the queue with at most limit of the waiting
+// subtasks, the oldest first.
+func (q *queue) snapshot(limit int, now time.Time) websocket.QueueSnapshot {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	result := websocket.QueueSnapshot{Time: now.Format("2006-01-02 15:04:05"), Depth: make(map[string]int), Subtasks: []websocket.QueuedSubtask{}}
+	for _, op := range operations {
+		result.Depth[op] = 0
+	}
+	var oldest time.Duration
+	for _, name := range q.poolNames() {
+		p := q.pools[name]
+		next := p.next()
+		for _, e := range p.items {
+			wait := now.Sub(e.queued)
+			oldest = max(oldest, wait)
+			result.Depth[e.s.substack.op]++
+			result.Subtasks = append(result.Subtasks, websocket.QueuedSubtask{
+				Key:        e.s.key,
+				TaskId:     e.s.taskId,
+				UserId:     e.s.userId,
+				Operation:  e.s.substack.op,
+				Expression: e.s.substack.value,
+				Pool:       name,
+				Priority:   e.s.priority,
+				Queued:     e.queued.Format("2006-01-02 15:04:05.000"),
+				Wait:       wait.Round(time.Millisecond).String(),
+				WaitMs:     wait.Milliseconds(),
+				Next:       e == next,
+			})
+		}
+	}
+	sort.SliceStable(result.Subtasks, func(i, j int) bool {
+		return result.Subtasks[i].WaitMs > result.Subtasks[j].WaitMs
+	})
+	result.Total = len(result.Subtasks)
+	if len(result.Subtasks) > limit {
+		result.Subtasks, result.Truncated = result.Subtasks[:limit], true
+	}
+	result.OldestWait, result.OldestWaitMs = oldest.Round(time.Millisecond).String(), oldest.Milliseconds()
+	return result
+}
+
+// GetQueue returns the subtasks waiting for a worker and the queue depth of
+// every operation.
+func (c *Calculator) GetQueue() websocket.QueueSnapshot {
+	return c.toProcess.snapshot(c.inspector.cfg.MaxItems, time.Now())
+}
+
+// streamQueue sends the admins the state of the queue every interval while
+// subtasks wait, and once more when the queue empties.
+func (c *Calculator) streamQueue() {
+	ticker := time.NewTicker(c.inspector.cfg.Interval)
+	defer ticker.Stop()
+	empty := true
+	for {
+		select {
+		case <-c.stopping:
+			return
+		case <-ticker.C:
+		}
+		snapshot := c.GetQueue()
+		if snapshot.Total == 0 && empty {
+			continue
+		}
+		empty = snapshot.Total == 0
+		c.Updates <- *websocket.QueueMessage(&snapshot)
+	}
+}
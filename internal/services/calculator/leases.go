@@ -0,0 +1,354 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/config"
+)
+
+var ErrLeaseLost = errors.New("subtask lease is lost")
+
+// lease gives a worker or an agent the right to compute a subtask until the
+// deadline. The holder has to heartbeat to keep it. A subtask has more than
+// one lease while a speculative copy of it is running.
+type lease struct {
+	subtask  *Subtask
+	holder   int
+	started  time.Time
+	deadline time.Time
+}
+
+type speculationStats struct {
+	Launched  int `json:"launched"`
+	Helped    int `json:"helped"`
+	Wasted    int `json:"wasted"`
+	Cancelled int `json:"cancelled"`
+}
+
+// skipDelay is how long a subtask the holder may not take waits before it is
+// queued again, so the holder does not pick it up in a loop.
+const skipDelay = 100 * time.Millisecond
+
+type leases struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	list        map[int][]*lease
+	speculative map[int]bool
+	stats       speculationStats
+	quorum      *quorum
+	// skipped is called with a subtask the holder had to skip.
+	skipped func(*Subtask)
+}
+
+func newLeases(cfg config.LeasesConfig, q *quorum) *leases {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 15 * time.Second
+	}
+	return &leases{ttl: cfg.TTL, list: make(map[int][]*lease), speculative: make(map[int]bool), quorum: q}
+}
+
+// heartbeatInterval is how often holders are expected to heartbeat.
+func (l *leases) heartbeatInterval() time.Duration {
+	return l.ttl / 3
+}
+
+// take grants the holder a lease for a subtask it received from a pool.
+// It returns false if the holder must skip the subtask: another copy of it
+// is already finished or the holder computes or has computed another copy.
+// The first time a subtask that needs a quorum is taken, the other copies
+// of it are queued.
+func (l *leases) take(q *queue, s *Subtask, holder int) bool {
+	if s.finished.Load() {
+		return false
+	}
+	if s.isCancelled() {
+		s.abandon()
+		return false
+	}
+	l.mu.Lock()
+	if l.skips(s, holder) {
+		l.mu.Unlock()
+		l.skipped(s)
+		return false
+	}
+	defer l.mu.Unlock()
+	id, copies, ok := l.quorum.open(s.key, s.substack.op, s.ballot)
+	if !ok {
+		return false
+	}
+	s.ballot = id
+	for ; copies > 0; copies-- {
+		// The copies share the state of the subtask, see Subtask.
+		dup := *s
+		q.push(&dup)
+	}
+	now := time.Now()
+	l.list[s.key] = append(l.list[s.key], &lease{subtask: s, holder: holder, started: now, deadline: now.Add(l.ttl)})
+	return true
+}
+
+// skips reports whether the holder may not take the copy of the subtask.
+// l.mu must be held.
+func (l *leases) skips(s *Subtask, holder int) bool {
+	return s.avoid == holder || l.find(s.key, holder) != nil || l.quorum.voted(s.key, holder)
+}
+
+// find returns the lease of the holder. l.mu must be held.
+func (l *leases) find(key, holder int) *lease {
+	for _, v := range l.list[key] {
+		if v.holder == holder {
+			return v
+		}
+	}
+	return nil
+}
+
+// heartbeat extends the lease. It returns false if the holder lost it.
+func (l *leases) heartbeat(key, holder int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	v := l.find(key, holder)
+	if v == nil {
+		return false
+	}
+	v.deadline = time.Now().Add(l.ttl)
+	return true
+}
+
+// release ends the lease and returns the subtask if the holder still has it.
+// Leases of the other copies end as well, so their holders stop computing.
+func (l *leases) release(key, holder int) (*Subtask, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	v := l.find(key, holder)
+	if v == nil {
+		return nil, false
+	}
+	if l.speculative[key] {
+		if v.subtask.avoid != 0 {
+			l.stats.Helped++
+		} else {
+			l.stats.Wasted++
+		}
+		l.stats.Cancelled += len(l.list[key]) - 1
+		delete(l.speculative, key)
+	}
+	delete(l.list, key)
+	return v.subtask, true
+}
+
+// drop ends only the lease of the holder and returns its copy of the subtask.
+func (l *leases) drop(key, holder int) (*Subtask, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	list := l.list[key]
+	for i, v := range list {
+		if v.holder != holder {
+			continue
+		}
+		if len(list) == 1 {
+			delete(l.list, key)
+			delete(l.speculative, key)
+		} else {
+			l.list[key] = append(list[:i:i], list[i+1:]...)
+		}
+		return v.subtask, true
+	}
+	return nil, false
+}
+
+// cancel ends the leases of all copies of the subtask.
+func (l *leases) cancel(key int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.list, key)
+	delete(l.speculative, key)
+}
+
+// cancelTask ends the leases of the subtasks of the task and returns the
+// subtasks.
+func (l *leases) cancelTask(taskId int) []*Subtask {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := []*Subtask{}
+	for k, list := range l.list {
+		if list[0].subtask.taskId != taskId {
+			continue
+		}
+		result = append(result, list[0].subtask)
+		delete(l.list, k)
+		delete(l.speculative, k)
+		l.quorum.close(k)
+	}
+	return result
+}
+
+// count returns the number of subtasks being computed.
+func (l *leases) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.list)
+}
+
+// held returns the subtasks leased by the holder.
+func (l *leases) held(holder int) []*Subtask {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := []*Subtask{}
+	for _, list := range l.list {
+		for _, v := range list {
+			if v.holder == holder {
+				result = append(result, v.subtask)
+			}
+		}
+	}
+	return result
+}
+
+// expired removes the leases whose deadline has passed and returns them.
+// The subtask of an expired lease is returned only if no other copy of it is
+// still being computed, unless the copies are verified by a quorum and each
+// of them counts.
+func (l *leases) expired() []*lease {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := []*lease{}
+	for k, list := range l.list {
+		alive := []*lease{}
+		verified := l.quorum.pending(k)
+		for _, v := range list {
+			if !time.Now().After(v.deadline) {
+				alive = append(alive, v)
+			} else if verified {
+				result = append(result, v)
+			}
+		}
+		if len(alive) == 0 && !verified {
+			result = append(result, list[0])
+			delete(l.list, k)
+			delete(l.speculative, k)
+		} else {
+			l.list[k] = alive
+		}
+		if len(alive) == 0 && verified {
+			delete(l.list, k)
+		}
+	}
+	return result
+}
+
+// stragglers returns the leases that run longer than expected and have no
+// speculative copy yet.
+func (l *leases) stragglers(expected func(*Subtask) time.Duration) []*lease {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := []*lease{}
+	for k, list := range l.list {
+		if l.speculative[k] || len(list) != 1 || l.quorum.pending(k) {
+			continue
+		}
+		if time.Since(list[0].started) > expected(list[0].subtask) {
+			result = append(result, list[0])
+		}
+	}
+	return result
+}
+
+// speculate marks the straggler as speculated. It returns false if the lease
+// has ended or got another copy in the meantime.
+func (l *leases) speculate(v *lease) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	list := l.list[v.subtask.key]
+	if l.speculative[v.subtask.key] || len(list) != 1 || list[0] != v {
+		return false
+	}
+	l.speculative[v.subtask.key] = true
+	l.stats.Launched++
+	return true
+}
+
+func (l *leases) speculationStats() speculationStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+func (c *Calculator) reapLeases() {
+	ticker := time.NewTicker(c.leases.heartbeatInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopping:
+			return
+		case <-ticker.C:
+		}
+		for _, v := range c.leases.expired() {
+			if v.subtask.finished.Load() || (!c.leases.quorum.pending(v.subtask.key) && c.toProcess.has(v.subtask.key)) {
+				continue
+			}
+			c.reassign(v.subtask, fmt.Sprintf("lease of worker %d expired", v.holder))
+		}
+	}
+}
+
+// skipped queues a copy the holder had to skip again, unless no other holder
+// is left that may take it. Then a speculative copy is dropped and a quorum
+// that cannot be reached counts as a failed attempt at the subtask.
+func (c *Calculator) skipped(s *Subtask) {
+	if c.otherHolder(s) {
+		time.AfterFunc(skipDelay, func() {
+			c.toProcess.push(s)
+		})
+		return
+	}
+	if c.leases.quorum.pending(s.key) {
+		c.abortBallot(s, "not enough distinct workers for the quorum")
+		return
+	}
+	c.note(s, "no other worker may take the copy, copy dropped")
+}
+
+// otherHolder reports whether a worker or an online agent that may take the
+// copy of the subtask exists, busy or not.
+func (c *Calculator) otherHolder(s *Subtask) bool {
+	p := c.toProcess.route(s.substack.op)
+	holders := c.Worker.active(p.name)
+	if p.overflow {
+		holders = append(holders, c.Worker.active(sharedPool)...)
+	}
+	if p.overflow || p.name == sharedPool {
+		holders = append(holders, c.onlineAgents()...)
+	}
+	c.leases.mu.Lock()
+	defer c.leases.mu.Unlock()
+	for _, h := range holders {
+		if !c.leases.skips(s, h) && !c.leases.quorum.isQuarantined(h) {
+			return true
+		}
+	}
+	return false
+}
+
+// report accepts the result of a subtask from its holder. It returns false
+// if the holder no longer has the lease.
+func (c *Calculator) report(key, holder int, result string) bool {
+	c.reporting.Add(1)
+	defer c.reporting.Add(-1)
+	if !c.leases.quorum.pending(key) {
+		s, ok := c.leases.release(key, holder)
+		if ok {
+			s.complete(result)
+		}
+		return ok
+	}
+	s, ok := c.leases.drop(key, holder)
+	if !ok {
+		return false
+	}
+	c.vote(s, holder, result)
+	return true
+}
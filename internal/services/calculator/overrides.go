@@ -0,0 +1,119 @@
+package calculator
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/apple5343/golangProjectV2/internal/domain/models"
+)
+
+// Where the delay of a user comes from.
+const (
+	delaySourceUser   = "user"
+	delaySourceGroup  = "group"
+	delaySourceGlobal = "global"
+)
+
+var ErrDelayGroup = errors.New("delay group name is empty")
+
+// forUser returns the delay of the operation for a subtask of the user whose
+// task started with the version, and where the delay comes from.
+func (d *delays) forUser(userID, version int, op string) (models.Delay, string) {
+	d.mu.RLock()
+	overrides := d.overrides
+	d.mu.RUnlock()
+	if v, ok := overrides.Users[userID][op]; ok {
+		return v, delaySourceUser
+	}
+	if group, ok := overrides.Members[userID]; ok {
+		if v, ok := overrides.Groups[group][op]; ok {
+			return v, delaySourceGroup
+		}
+	}
+	return d.of(version, op), delaySourceGlobal
+}
+
+// delayOf returns the delay the subtask takes.
+func (c *Calculator) delayOf(s *Subtask) models.Delay {
+	d, _ := c.delays.forUser(s.userId, s.delayVersion, s.substack.op)
+	return d
+}
+
+// reloadOverrides reads the overrides after a change. c.delays.mu must be
+// held.
+func (c *Calculator) reloadOverrides() error {
+	overrides, err := c.db.GetDelayOverrides()
+	if err != nil {
+		return err
+	}
+	c.delays.overrides = overrides
+	return nil
+}
+
+func (c *Calculator) GetDelayOverrides() models.DelayOverrides {
+	c.delays.mu.RLock()
+	defer c.delays.mu.RUnlock()
+	return c.delays.overrides
+}
+
+// SetUserDelays replaces the delay overrides of the user, no delays remove
+// them. They apply to the subtasks of the user that are taken from now on.
+func (c *Calculator) SetUserDelays(userID int, delays map[string]models.Delay) error {
+	if err := checkDelays(delays); err != nil {
+		return err
+	}
+	c.delays.mu.Lock()
+	defer c.delays.mu.Unlock()
+	if err := c.db.SetUserDelays(userID, delays); err != nil {
+		return err
+	}
+	return c.reloadOverrides()
+}
+
+// SetGroupDelays replaces the delay overrides of the group, no delays remove
+// them.
+func (c *Calculator) SetGroupDelays(group string, delays map[string]models.Delay) error {
+	group = strings.TrimSpace(group)
+	if group == "" {
+		return ErrDelayGroup
+	}
+	if err := checkDelays(delays); err != nil {
+		return err
+	}
+	c.delays.mu.Lock()
+	defer c.delays.mu.Unlock()
+	if err := c.db.SetGroupDelays(group, delays); err != nil {
+		return err
+	}
+	return c.reloadOverrides()
+}
+
+// SetUserDelayGroup puts the user in the delay group, an empty group takes
+// the user out of the group.
+func (c *Calculator) SetUserDelayGroup(userID int, group string) error {
+	c.delays.mu.Lock()
+	defer c.delays.mu.Unlock()
+	if err := c.db.SetUserDelayGroup(userID, strings.TrimSpace(group)); err != nil {
+		return err
+	}
+	return c.reloadOverrides()
+}
+
+// GetUserDelays returns the delays the next task of the user runs with,
+// where each of them comes from and the delay group of the user.
+func (c *Calculator) GetUserDelays(userID int) map[string]interface{} {
+	version := c.delays.current().ID
+	delays := make(map[string]models.Delay)
+	sources := make(map[string]string)
+	for _, op := range operations {
+		delays[op], sources[op] = c.delays.forUser(userID, version, op)
+	}
+	c.delays.mu.RLock()
+	group := c.delays.overrides.Members[userID]
+	c.delays.mu.RUnlock()
+	return map[string]interface{}{
+		"delays":  delays,
+		"sources": sources,
+		"group":   group,
+	}
+}
@@ -0,0 +1,149 @@
+package calculator
+
+import (
+	"errors"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/domain/models"
+	storage "github.com/apple5343/golangProjectV2/internal/storage/sqlite"
+)
+
+var ErrTaskNotPaused = errors.New("task is not paused")
+
+// pause marks the running task paused. It returns false if the task is not
+// running.
+func (t *tasks) pause(id int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	task, ok := t.list[id]
+	if ok {
+		task.paused = true
+	}
+	return ok
+}
+
+// unpause clears the pause of a task that has not stopped yet. It returns
+// false if the task has already stopped.
+func (t *tasks) unpause(id int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	task, ok := t.list[id]
+	if ok {
+		task.paused = false
+	}
+	return ok
+}
+
+// stopIfPaused forgets the task and returns true if it is paused.
+func (t *tasks) stopIfPaused(id int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	task, ok := t.list[id]
+	if !ok || !task.paused {
+		return false
+	}
+	delete(t.list, id)
+	return true
+}
+
+// own returns the owner and the status of the task if the user may change
+// it. Admins may change any task.
+func (c *Calculator) own(taskID, userID int) (int, string, error) {
+	owner, status, err := c.db.GetTaskOwner(taskID)
+	if err != nil {
+		return 0, "", err
+	}
+	if owner != userID {
+		admin, err := c.db.IsAdmin(userID)
+		if err != nil {
+			return 0, "", err
+		}
+		if !admin {
+			return 0, "", storage.ErrTaskNotFound
+		}
+	}
+	return owner, status, nil
+}
+
+// PauseTask stops the task of the user after its current round. The
+// subtasks of the round are still computed.
+func (c *Calculator) PauseTask(taskID, userID int) error {
+	owner, status, err := c.own(taskID, userID)
+	if err != nil {
+		return err
+	}
+	if !canTransition(status, models.StatusPaused) || !c.tasks.pause(taskID) {
+		return ErrTaskNotRunning
+	}
+	if err := setStatus(c.db, taskID, models.StatusPaused, byUser(userID)); err != nil {
+		c.tasks.unpause(taskID)
+		return err
+	}
+	c.UpdatesTask <- TaskUpdate{owner, taskID, time.Now().Format("2006-01-02 15:04:05"), 0, "", models.StatusPaused}
+	return nil
+}
+
+// ResumeTask continues the paused task of the user from its last step.
+func (c *Calculator) ResumeTask(taskID, userID int) error {
+	owner, status, err := c.own(taskID, userID)
+	if err != nil {
+		return err
+	}
+	if status != models.StatusPaused {
+		return ErrTaskNotPaused
+	}
+	if c.isStopping() {
+		return ErrShuttingDown
+	}
+	c.quotas.mu.Lock()
+	defer c.quotas.mu.Unlock()
+	if err := c.checkRunning(owner); err != nil {
+		return err
+	}
+	if err := setStatus(c.db, taskID, models.StatusProcessing, byUser(userID), models.StatusPaused); err != nil {
+		return err
+	}
+	if !c.tasks.unpause(taskID) {
+		if err := c.resume(taskID, owner); err != nil {
+			return err
+		}
+	}
+	c.UpdatesTask <- TaskUpdate{owner, taskID, time.Now().Format("2006-01-02 15:04:05"), 0, "", models.StatusProcessing}
+	return nil
+}
+
+// resume starts the task again. A journaled round is continued with the
+// results computed so far, otherwise the task starts from its last step.
+func (c *Calculator) resume(taskID, userID int) error {
+	info, err := c.db.GetTaskById(int64(taskID), int64(userID))
+	if err != nil {
+		return err
+	}
+	created, err := time.Parse("2006-01-02 15:04:05", info["created"].(string))
+	if err != nil {
+		return err
+	}
+	lastStep := info["lastStep"].(string)
+	journal, err := c.db.GetJournal(taskID)
+	if err != nil {
+		return err
+	}
+	round := lastStep
+	var restored map[int]string
+	if len(journal) > 0 {
+		round = journal[0].Round
+		restored = make(map[int]string)
+		for _, v := range journal {
+			if v.Done {
+				restored[v.SymbolID] = v.Result
+			}
+		}
+	}
+	task := &Task{subtask: NewSpliter(round), toProcess: c.toProcess, Expression: lastStep, db: c.db, Created: created, Id: taskID,
+		UserID: userID, Priority: info["priority"].(string), UpdateCh: c.UpdatesTask, tasks: c.tasks, cancelled: make(chan struct{}),
+		DelayVersion: info["delayVersion"].(int), round: round, restored: restored}
+	task.started.Store(info["status"] != models.StatusQueued)
+	c.tasks.add(task)
+	go task.Start()
+	return nil
+}
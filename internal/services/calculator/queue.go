@@ -0,0 +1,416 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/config"
+)
+
+// sharedPool takes the operations that have no dedicated pool. Remote agents
+// belong to it as well.
+const sharedPool = "shared"
+
+const (
+	priorityLow    = "low"
+	priorityNormal = "normal"
+	priorityHigh   = "high"
+
+	maxWeight = 100
+)
+
+// priorities orders the priority levels, higher levels are dispatched first.
+var priorities = map[string]int{priorityLow: 0, priorityNormal: 1, priorityHigh: 2}
+
+var (
+	ErrPoolNotFound = errors.New("pool not found")
+	ErrPriority     = errors.New("unknown priority")
+	ErrHighPriority = errors.New("high priority is only available to admins")
+	ErrWeight       = fmt.Errorf("weight must be between 1 and %d", maxWeight)
+)
+
+// pool is a group of workers that compute only the operations routed to it.
+// If overflow is set, the shared pool may take its subtasks too.
+type pool struct {
+	name       string
+	operations []string
+	ch         chan *Subtask
+	overflow   bool
+	// wake is signalled when a subtask is queued for the pool.
+	wake chan struct{}
+	// items are the waiting subtasks in the order they were queued.
+	items []*entry
+	// pass is how much of the pool each user has used, divided by the
+	// weight of the user. The user with the smallest pass goes next.
+	pass  map[int]float64
+	clock float64
+}
+
+type entry struct {
+	s      *Subtask
+	queued time.Time
+}
+
+// queue is the scheduler that hands subtasks over to workers. Every pool has
+// a dispatcher that offers its workers the subtask of the highest priority
+// and, within a priority, the subtask of the user who has used the pool the
+// least relative to the weight of the user.
+type queue struct {
+	mu      sync.Mutex
+	pools   map[string]*pool
+	routes  map[string]string
+	weights map[int]int
+	lastKey int
+	// stopped is closed when the queue stops dispatching subtasks.
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func newQueue(cfg []config.PoolConfig) (*queue, error) {
+	q := &queue{
+		pools:   map[string]*pool{sharedPool: newPool(sharedPool, nil, false)},
+		routes:  make(map[string]string),
+		weights: make(map[int]int),
+		stopped: make(chan struct{}),
+	}
+	for _, v := range cfg {
+		if v.Name == "" {
+			return nil, fmt.Errorf("pool name is empty")
+		}
+		if _, ok := q.pools[v.Name]; ok {
+			return nil, fmt.Errorf("pool %s is configured twice", v.Name)
+		}
+		for _, op := range v.Operations {
+			if !knownOperation(op) {
+				return nil, fmt.Errorf("pool %s: %w: %s", v.Name, ErrUnknownOperation, op)
+			}
+			if other, ok := q.routes[op]; ok {
+				return nil, fmt.Errorf("operation %s belongs to pools %s and %s", op, other, v.Name)
+			}
+			q.routes[op] = v.Name
+		}
+		q.pools[v.Name] = newPool(v.Name, v.Operations, v.Overflow)
+	}
+	for _, p := range q.pools {
+		go q.dispatch(p)
+	}
+	return q, nil
+}
+
+func newPool(name string, ops []string, overflow bool) *pool {
+	return &pool{name: name, operations: ops, ch: make(chan *Subtask), overflow: overflow, wake: make(chan struct{}, 1), pass: make(map[int]float64)}
+}
+
+func knownOperation(op string) bool {
+	for _, v := range operations {
+		if v == op {
+			return true
+		}
+	}
+	return false
+}
+
+// route returns the pool the operation is computed in.
+func (q *queue) route(op string) *pool {
+	if name, ok := q.routes[op]; ok {
+		return q.pools[name]
+	}
+	return q.pools[sharedPool]
+}
+
+// ch returns the channel the workers of the pool receive subtasks from.
+func (q *queue) ch(name string) <-chan *Subtask {
+	return q.pools[name].ch
+}
+
+// push queues the subtask in its pool. Every subtask gets a key on its first
+// push, which identifies it while it is being computed.
+func (q *queue) push(s *Subtask) {
+	if s.isCancelled() {
+		s.abandon()
+		return
+	}
+	p := q.route(s.substack.op)
+	q.mu.Lock()
+	if s.key == 0 {
+		q.lastKey++
+		s.key = q.lastKey
+	}
+	if !p.waiting(s.userId) {
+		p.pass[s.userId] = max(p.pass[s.userId], p.clock)
+	}
+	p.items = append(p.items, &entry{s: s, queued: time.Now()})
+	q.mu.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// waiting reports whether the user has subtasks in the pool. q.mu must be held.
+func (p *pool) waiting(userId int) bool {
+	for _, e := range p.items {
+		if e.s.userId == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// next returns the subtask that should be dispatched next. q.mu must be held.
+func (p *pool) next() *entry {
+	var best *entry
+	for _, e := range p.items {
+		if best == nil {
+			best = e
+			continue
+		}
+		pe, pb := priorities[e.s.priority], priorities[best.s.priority]
+		if pe > pb || (pe == pb && p.pass[e.s.userId] < p.pass[best.s.userId]) {
+			best = e
+		}
+	}
+	return best
+}
+
+// dispatch offers the next subtask of the pool to its workers until one of
+// them takes it. If another subtask is queued meanwhile, the choice is made
+// again.
+func (q *queue) dispatch(p *pool) {
+	shared := q.pools[sharedPool].ch
+	for {
+		q.mu.Lock()
+		e := p.next()
+		q.mu.Unlock()
+		if e == nil {
+			select {
+			case <-p.wake:
+			case <-q.stopped:
+				return
+			}
+			continue
+		}
+		var overflow chan *Subtask
+		if p.overflow {
+			overflow = shared
+		}
+		select {
+		case p.ch <- e.s:
+		case overflow <- e.s:
+		case <-p.wake:
+			continue
+		case <-q.stopped:
+			return
+		}
+		// The entry stays queued while it is offered, so it may have been
+		// withdrawn meanwhile. Then it does not count against the user.
+		q.mu.Lock()
+		if p.remove(e) {
+			p.clock = p.pass[e.s.userId]
+			p.pass[e.s.userId] += 1 / float64(q.weight(e.s.userId))
+		}
+		q.mu.Unlock()
+	}
+}
+
+// stop ends dispatching. Queued subtasks stay in their pools.
+func (q *queue) stop() {
+	q.stopOnce.Do(func() { close(q.stopped) })
+}
+
+// remove deletes the entry from the pool. It returns false if the entry is
+// no longer there. q.mu must be held.
+func (p *pool) remove(e *entry) bool {
+	for i, v := range p.items {
+		if v == e {
+			p.items = append(p.items[:i], p.items[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// weight returns the fair share weight of the user. q.mu must be held.
+func (q *queue) weight(userId int) int {
+	if w, ok := q.weights[userId]; ok {
+		return w
+	}
+	return 1
+}
+
+func (q *queue) setWeight(userId, weight int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.weights[userId] = weight
+}
+
+// withdraw removes the waiting subtasks of the task and returns them.
+func (q *queue) withdraw(taskId int) []*Subtask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	result := []*Subtask{}
+	for _, p := range q.pools {
+		items := p.items[:0]
+		for _, e := range p.items {
+			if e.s.taskId == taskId {
+				result = append(result, e.s)
+			} else {
+				items = append(items, e)
+			}
+		}
+		p.items = items
+	}
+	return result
+}
+
+// discard removes the waiting copies of the subtask with the key.
+func (q *queue) discard(key int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, p := range q.pools {
+		items := p.items[:0]
+		for _, e := range p.items {
+			if e.s.key != key {
+				items = append(items, e)
+			}
+		}
+		p.items = items
+	}
+}
+
+// has reports whether the subtask with the key is waiting in the queue.
+func (q *queue) has(key int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, p := range q.pools {
+		for _, e := range p.items {
+			if e.s.key == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stats returns the number of subtasks waiting for the pool and how long the
+// oldest of them has been waiting.
+func (q *queue) stats(name string) (int, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	p := q.pools[name]
+	var oldest time.Duration
+	for _, e := range p.items {
+		if wait := time.Since(e.queued); wait > oldest {
+			oldest = wait
+		}
+	}
+	return len(p.items), oldest
+}
+
+// poolNames returns the names of the pools, the shared pool first.
+func (q *queue) poolNames() []string {
+	names := []string{}
+	for k := range q.pools {
+		if k != sharedPool {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{sharedPool}, names...)
+}
+
+type waitingGroup struct {
+	Pool       string `json:"pool"`
+	Priority   string `json:"priority"`
+	UserId     int    `json:"userId"`
+	Weight     int    `json:"weight"`
+	Waiting    int    `json:"waiting"`
+	OldestWait string `json:"oldestWait"`
+	Reason     string `json:"reason"`
+}
+
+// waitingGroups describes the waiting subtasks grouped by pool, priority and
+// user, and why each group is waiting.
+func (q *queue) waitingGroups() []waitingGroup {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	result := []waitingGroup{}
+	for _, name := range q.poolNames() {
+		p := q.pools[name]
+		next := p.next()
+		top := -1
+		groups := map[[2]interface{}]*waitingGroup{}
+		oldest := map[*waitingGroup]time.Time{}
+		order := []*waitingGroup{}
+		for _, e := range p.items {
+			top = max(top, priorities[e.s.priority])
+			k := [2]interface{}{e.s.priority, e.s.userId}
+			g, ok := groups[k]
+			if !ok {
+				g = &waitingGroup{Pool: name, Priority: e.s.priority, UserId: e.s.userId, Weight: q.weight(e.s.userId)}
+				groups[k] = g
+				oldest[g] = e.queued
+				order = append(order, g)
+			}
+			g.Waiting++
+		}
+		for _, g := range order {
+			g.OldestWait = time.Since(oldest[g]).Round(time.Millisecond).String()
+			switch {
+			case g.Priority == next.s.priority && g.UserId == next.s.userId:
+				g.Reason = "next, waiting for a free worker"
+			case priorities[g.Priority] < top:
+				g.Reason = "higher priority subtasks are waiting"
+			default:
+				g.Reason = fmt.Sprintf("user %d has used less of its fair share and goes first", next.s.userId)
+			}
+			result = append(result, *g)
+		}
+	}
+	return result
+}
+
+func (c *Calculator) poolsInfo() []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for _, name := range c.toProcess.poolNames() {
+		p := c.toProcess.pools[name]
+		ops := p.operations
+		if name == sharedPool {
+			ops = []string{}
+			for _, op := range operations {
+				if _, ok := c.toProcess.routes[op]; !ok {
+					ops = append(ops, op)
+				}
+			}
+			sort.Strings(ops)
+		}
+		depth, wait := c.toProcess.stats(name)
+		active, idle := c.Worker.counts(name)
+		result = append(result, map[string]interface{}{
+			"name":       name,
+			"operations": ops,
+			"overflow":   p.overflow,
+			"workers":    active,
+			"idle":       idle,
+			"queueDepth": depth,
+			"oldestWait": wait.Round(time.Millisecond).String(),
+		})
+	}
+	return result
+}
+
+// SetUserWeight changes the share of the workers the user gets when several
+// users have subtasks waiting.
+func (c *Calculator) SetUserWeight(userId, weight int) error {
+	if weight < 1 || weight > maxWeight {
+		return ErrWeight
+	}
+	if err := c.db.SetUserWeight(userId, weight); err != nil {
+		return err
+	}
+	c.toProcess.setWeight(userId, weight)
+	return nil
+}
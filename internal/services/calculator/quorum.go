@@ -0,0 +1,340 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/config"
+)
+
+var (
+	ErrQuorumSize       = errors.New("quorum size must be at least 2")
+	ErrUnknownOperation = errors.New("unknown operation")
+)
+
+// ballot collects the results of the copies of one subtask.
+type ballot struct {
+	id int
+	// size is the quorum size when the ballot was opened.
+	size   int
+	needed int
+	votes  map[int]string
+}
+
+type verdict struct {
+	done       bool
+	retry      bool
+	failed     bool
+	result     string
+	votes      map[int]string
+	dissenters []int
+}
+
+type quorumStats struct {
+	Verified      int `json:"verified"`
+	Disagreements int `json:"disagreements"`
+}
+
+// quorum makes several distinct holders compute the same subtask and accepts
+// the result the majority agrees on. Holders that disagree with the majority
+// too often are quarantined.
+type quorum struct {
+	mu              sync.Mutex
+	all             bool
+	operations      map[string]bool
+	size            int
+	quarantineAfter int
+	ballots         map[int]*ballot
+	lastBallot      int
+	strikes         map[int]int
+	quarantined     map[int]bool
+	stats           quorumStats
+}
+
+func newQuorum(cfg config.QuorumConfig) *quorum {
+	if cfg.Size < 2 {
+		cfg.Size = 3
+	}
+	if cfg.QuarantineAfter <= 0 {
+		cfg.QuarantineAfter = 3
+	}
+	q := &quorum{
+		all:             cfg.All,
+		operations:      make(map[string]bool),
+		size:            cfg.Size,
+		quarantineAfter: cfg.QuarantineAfter,
+		ballots:         make(map[int]*ballot),
+		strikes:         make(map[int]int),
+		quarantined:     make(map[int]bool),
+	}
+	for _, v := range cfg.Operations {
+		q.operations[v] = true
+	}
+	return q
+}
+
+// set changes which operations are verified. It does not affect subtasks
+// that are already being verified.
+func (q *quorum) set(all bool, ops []string, size int) error {
+	if size < 2 {
+		return ErrQuorumSize
+	}
+	list := make(map[string]bool)
+	for _, v := range ops {
+		if !knownOperation(v) {
+			return fmt.Errorf("%w: %s", ErrUnknownOperation, v)
+		}
+		list[v] = true
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.all = all
+	q.operations = list
+	q.size = size
+	return nil
+}
+
+// open starts a ballot for the subtask if its operation is verified and it
+// has none yet. It returns the ballot the copy with the ballot id joins and
+// how many more copies of the subtask must be queued. A copy made for a ballot
+// that has been closed since is stale: open returns false and the copy must
+// be dropped, or it would open a ballot of its own next to the retry.
+func (q *quorum) open(key int, op string, id int) (int, int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if b, ok := q.ballots[key]; ok {
+		return b.id, 0, id == 0 || id == b.id
+	}
+	if id != 0 {
+		return 0, 0, false
+	}
+	if !(q.all || q.operations[op]) {
+		return 0, 0, true
+	}
+	q.lastBallot++
+	q.ballots[key] = &ballot{id: q.lastBallot, size: q.size, needed: q.size, votes: make(map[int]string)}
+	return q.lastBallot, q.size - 1, true
+}
+
+// pending reports whether the subtask is being verified.
+func (q *quorum) pending(key int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, ok := q.ballots[key]
+	return ok
+}
+
+// close drops the ballot of the subtask without a verdict. It returns false
+// if there was none.
+func (q *quorum) close(key int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, ok := q.ballots[key]
+	delete(q.ballots, key)
+	return ok
+}
+
+func (q *quorum) voted(key, holder int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	b, ok := q.ballots[key]
+	if !ok {
+		return false
+	}
+	_, ok = b.votes[holder]
+	return ok
+}
+
+// vote records the result of the holder. The result is accepted as soon as
+// a majority of the quorum agrees on it. If all copies are in and there is no
+// majority, one more copy is requested, up to twice the quorum size, after
+// which the ballot fails and has to be closed by the caller.
+func (q *quorum) vote(key, holder int, result string) verdict {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	b, ok := q.ballots[key]
+	if !ok {
+		return verdict{}
+	}
+	b.votes[holder] = result
+	tally := map[string]int{}
+	best := ""
+	for _, v := range b.votes {
+		tally[v]++
+		if tally[v] > tally[best] || (tally[v] == tally[best] && v < best) {
+			best = v
+		}
+	}
+	majority := b.size/2 + 1
+	if tally[best] < majority && len(b.votes) < b.needed {
+		return verdict{}
+	}
+	if tally[best] < majority && b.needed < 2*b.size {
+		b.needed++
+		return verdict{retry: true, votes: copyVotes(b.votes)}
+	}
+	if tally[best] < majority {
+		q.stats.Disagreements++
+		return verdict{failed: true, votes: copyVotes(b.votes)}
+	}
+	delete(q.ballots, key)
+	v := verdict{done: true, result: best, votes: copyVotes(b.votes)}
+	for h, r := range b.votes {
+		if r != best {
+			v.dissenters = append(v.dissenters, h)
+		}
+	}
+	sort.Ints(v.dissenters)
+	q.stats.Verified++
+	if len(v.dissenters) > 0 {
+		q.stats.Disagreements++
+	}
+	return v
+}
+
+func copyVotes(votes map[int]string) map[int]string {
+	result := make(map[int]string, len(votes))
+	for k, v := range votes {
+		result[k] = v
+	}
+	return result
+}
+
+// strike counts a disagreement of the holder. It returns true if the holder
+// has just been quarantined.
+func (q *quorum) strike(holder int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.strikes[holder]++
+	if q.quarantined[holder] || q.strikes[holder] < q.quarantineAfter {
+		return false
+	}
+	q.quarantined[holder] = true
+	return true
+}
+
+// lift takes the holder out of quarantine and forgets its strikes. It returns
+// false if the holder is not quarantined.
+func (q *quorum) lift(holder int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.quarantined[holder] {
+		return false
+	}
+	delete(q.quarantined, holder)
+	delete(q.strikes, holder)
+	return true
+}
+
+func (q *quorum) isQuarantined(holder int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.quarantined[holder]
+}
+
+func (q *quorum) strikesOf(holder int) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.strikes[holder]
+}
+
+func (q *quorum) info() map[string]interface{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ops := []string{}
+	for k := range q.operations {
+		ops = append(ops, k)
+	}
+	sort.Strings(ops)
+	quarantined := []int{}
+	for k := range q.quarantined {
+		quarantined = append(quarantined, k)
+	}
+	sort.Ints(quarantined)
+	return map[string]interface{}{
+		"all":             q.all,
+		"operations":      ops,
+		"size":            q.size,
+		"quarantineAfter": q.quarantineAfter,
+		"quarantined":     quarantined,
+		"stats":           q.stats,
+	}
+}
+
+func (c *Calculator) SetQuorum(all bool, ops []string, size int) error {
+	return c.leases.quorum.set(all, ops, size)
+}
+
+// Unquarantine lets the quarantined worker or agent take subtasks again.
+func (c *Calculator) Unquarantine(id int) error {
+	if !c.leases.quorum.lift(id) {
+		return ErrNotQuarantined
+	}
+	c.Worker.wake(id)
+	return nil
+}
+
+// vote counts the result of a verified subtask and completes the subtask
+// once the quorum has decided.
+func (c *Calculator) vote(s *Subtask, holder int, result string) {
+	v := c.leases.quorum.vote(s.key, holder, result)
+	switch {
+	case v.retry:
+		c.note(s, fmt.Sprintf("results disagree (%s), one more copy queued", formatVotes(v.votes)))
+		dup := *s
+		c.toProcess.push(&dup)
+	case v.failed:
+		c.abortBallot(s, fmt.Sprintf("results disagree (%s), no majority", formatVotes(v.votes)))
+	case v.done:
+		c.leases.cancel(s.key)
+		if len(v.dissenters) > 0 {
+			c.note(s, fmt.Sprintf("results disagree (%s), %s accepted", formatVotes(v.votes), v.result))
+		}
+		for _, h := range v.dissenters {
+			if c.leases.quorum.strike(h) {
+				c.note(s, fmt.Sprintf("worker %d quarantined", h))
+			}
+		}
+		s.complete(v.result)
+	}
+}
+
+// abortBallot drops the ballot of the subtask along with its queued and
+// running copies and counts it as a failed attempt at the subtask.
+func (c *Calculator) abortBallot(s *Subtask, reason string) {
+	if !c.leases.quorum.close(s.key) {
+		return
+	}
+	c.toProcess.discard(s.key)
+	c.leases.cancel(s.key)
+	// The retry starts a new ballot.
+	retry := *s
+	retry.ballot = 0
+	c.reassign(&retry, reason)
+}
+
+func formatVotes(votes map[int]string) string {
+	holders := []int{}
+	for k := range votes {
+		holders = append(holders, k)
+	}
+	sort.Ints(holders)
+	list := []string{}
+	for _, h := range holders {
+		list = append(list, fmt.Sprintf("worker %d: %s", h, votes[h]))
+	}
+	return strings.Join(list, ", ")
+}
+
+// note logs the message and records it in the step history of the task.
+func (c *Calculator) note(s *Subtask, message string) {
+	log.Printf("task %d: subtask %s: %s", s.taskId, s.substack.value, message)
+	if err := c.db.AddStepNote(s.taskId, s.substack.value, message, time.Now()); err != nil {
+		fmt.Println(err)
+	}
+}
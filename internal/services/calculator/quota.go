@@ -0,0 +1,165 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/config"
+	"github.com/apple5343/golangProjectV2/internal/domain/models"
+)
+
+// runningRetryAfter is suggested to a user who has too many running tasks,
+// as it is not known when one of them finishes.
+const runningRetryAfter = 10 * time.Second
+
+var (
+	ErrQuota    = errors.New("quota must be -1 for the global limit, 0 for no limit or positive")
+	ErrTaskCost = errors.New("task needs more delay than the daily quota allows")
+)
+
+// QuotaError is returned when a new task would exceed a quota of the user.
+type QuotaError struct {
+	Limit      string
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s quota exceeded, retry after %s", e.Limit, e.RetryAfter)
+}
+
+// quotas checks new tasks against the limits of their users. The lock is
+// held from the check until the task is stored, so concurrent submissions
+// cannot both pass the same limit.
+type quotas struct {
+	mu     sync.Mutex
+	global models.Quota
+}
+
+func newQuotas(cfg config.QuotasConfig) *quotas {
+	return &quotas{global: models.Quota{Concurrent: cfg.Concurrent, PerHour: cfg.PerHour, DelayPerDay: cfg.DelayPerDay}}
+}
+
+// limits returns the quota of the user with the global limits in place of
+// the ones that are not overridden.
+func (c *Calculator) limits(userID int) (models.Quota, error) {
+	q, err := c.db.GetUserQuota(userID)
+	if err != nil {
+		return q, err
+	}
+	if q.Concurrent < 0 {
+		q.Concurrent = c.quotas.global.Concurrent
+	}
+	if q.PerHour < 0 {
+		q.PerHour = c.quotas.global.PerHour
+	}
+	if q.DelayPerDay < 0 {
+		q.DelayPerDay = c.quotas.global.DelayPerDay
+	}
+	return q, nil
+}
+
+// cost is the total average delay in seconds of the operations of the
+// expression for the user with the delay version, rounded up.
+func (c *Calculator) cost(expression string, userID, version int) int {
+	symbols, _ := SplitExpression(expression)
+	cost := time.Duration(0)
+	for _, v := range symbols {
+		if v.expressionType == "operation" {
+			d, _ := c.delays.forUser(userID, version, operations[v.value])
+			cost += meanDelay(d)
+		}
+	}
+	return int((cost + time.Second - 1) / time.Second)
+}
+
+// checkQuota returns an error if a task of the cost would exceed a quota of
+// the user. c.quotas.mu must be held.
+func (c *Calculator) checkQuota(userID, cost int, now time.Time) error {
+	q, err := c.limits(userID)
+	if err != nil {
+		return err
+	}
+	if err := c.checkConcurrent(userID, q); err != nil {
+		return err
+	}
+	if q.PerHour > 0 {
+		tasks, err := c.db.GetTasksSince(userID, now.Add(-time.Hour))
+		if err != nil {
+			return err
+		}
+		if len(tasks) >= q.PerHour {
+			return &QuotaError{Limit: "tasks per hour", RetryAfter: retryAfter(tasks[len(tasks)-q.PerHour].Created, time.Hour, now)}
+		}
+	}
+	if q.DelayPerDay > 0 {
+		if cost > q.DelayPerDay {
+			return ErrTaskCost
+		}
+		tasks, err := c.db.GetTasksSince(userID, now.Add(-24*time.Hour))
+		if err != nil {
+			return err
+		}
+		used := 0
+		for _, v := range tasks {
+			used += v.Cost
+		}
+		// The oldest tasks leave the window first, so wait until enough
+		// of them have left.
+		for _, v := range tasks {
+			if used+cost <= q.DelayPerDay {
+				break
+			}
+			used -= v.Cost
+			if used+cost <= q.DelayPerDay {
+				return &QuotaError{Limit: "delay per day", RetryAfter: retryAfter(v.Created, 24*time.Hour, now)}
+			}
+		}
+	}
+	return nil
+}
+
+// checkRunning returns an error if the user may not have one more running
+// task. c.quotas.mu must be held.
+func (c *Calculator) checkRunning(userID int) error {
+	q, err := c.limits(userID)
+	if err != nil {
+		return err
+	}
+	return c.checkConcurrent(userID, q)
+}
+
+func (c *Calculator) checkConcurrent(userID int, q models.Quota) error {
+	if q.Concurrent <= 0 {
+		return nil
+	}
+	running, err := c.db.CountRunningTasks(userID)
+	if err != nil {
+		return err
+	}
+	if running >= q.Concurrent {
+		return &QuotaError{Limit: "concurrent tasks", RetryAfter: runningRetryAfter}
+	}
+	return nil
+}
+
+// retryAfter returns how long until the task created at the time leaves the
+// window, rounded up to a second.
+func retryAfter(created string, window time.Duration, now time.Time) time.Duration {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", created, time.Local)
+	if err != nil {
+		return window
+	}
+	wait := t.Add(window).Sub(now)
+	return max(wait.Truncate(time.Second)+time.Second, time.Second)
+}
+
+// SetUserQuota overrides the global limits for the user. -1 keeps the global
+// limit, 0 removes the limit.
+func (c *Calculator) SetUserQuota(userID, concurrent, perHour, delayPerDay int) error {
+	if concurrent < -1 || perHour < -1 || delayPerDay < -1 {
+		return ErrQuota
+	}
+	return c.db.SetUserQuota(userID, models.Quota{Concurrent: concurrent, PerHour: perHour, DelayPerDay: delayPerDay})
+}
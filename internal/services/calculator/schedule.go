@@ -0,0 +1,285 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/domain/models"
+	storage "github.com/apple5343/golangProjectV2/internal/storage/sqlite"
+)
+
+const (
+	scheduleActive = "active"
+	schedulePaused = "paused"
+	scheduleDone   = "done"
+
+	// Catch-up policies decide what happens to the runs a schedule missed
+	// while the server was down: skip drops them, once makes one run for
+	// all of them and all makes every one of them.
+	catchUpSkip = "skip"
+	catchUpOnce = "once"
+	catchUpAll  = "all"
+
+	scheduleInterval = time.Second
+	// missedAfter is how late a run may start without counting as missed.
+	missedAfter = time.Minute
+	// maxCatchUp limits how many missed runs the all policy makes at once.
+	maxCatchUp = 100
+
+	timeLayout = "2006-01-02 15:04:05"
+)
+
+var (
+	ErrScheduleTime       = errors.New("either a run time or a cron expression must be set")
+	ErrRunAt              = errors.New("run time must be in the future")
+	ErrCatchUp            = errors.New("catch-up policy must be skip, once or all")
+	ErrScheduleNotActive  = errors.New("schedule is not active")
+	ErrScheduleNotPaused  = errors.New("schedule is not paused")
+	ErrScheduleRecurrence = errors.New("cron expression never matches")
+)
+
+// ScheduleOptions describe what a schedule computes and when.
+type ScheduleOptions struct {
+	Expression string
+	Priority   string
+	RunAt      time.Time
+	Cron       string
+	CatchUp    string
+}
+
+// checkSchedule validates the options of a schedule of the user and fills in
+// the defaults.
+func (c *Calculator) checkSchedule(userID int, opts *ScheduleOptions) error {
+	opts.Expression = strings.ReplaceAll(opts.Expression, " ", "")
+	if !IsValidExpression(opts.Expression) {
+		return fmt.Errorf("выражение недопустимо")
+	}
+	if opts.Priority == "" {
+		opts.Priority = priorityNormal
+	}
+	if _, ok := priorities[opts.Priority]; !ok {
+		return ErrPriority
+	}
+	if opts.Priority == priorityHigh {
+		admin, err := c.db.IsAdmin(userID)
+		if err != nil {
+			return err
+		}
+		if !admin {
+			return ErrHighPriority
+		}
+	}
+	if opts.CatchUp == "" {
+		opts.CatchUp = catchUpOnce
+	}
+	if opts.CatchUp != catchUpSkip && opts.CatchUp != catchUpOnce && opts.CatchUp != catchUpAll {
+		return ErrCatchUp
+	}
+	if opts.RunAt.IsZero() == (opts.Cron == "") {
+		return ErrScheduleTime
+	}
+	if !opts.RunAt.IsZero() && !opts.RunAt.After(time.Now()) {
+		return ErrRunAt
+	}
+	if opts.Cron != "" {
+		spec, err := parseCron(opts.Cron)
+		if err != nil {
+			return err
+		}
+		if spec.next(time.Now()).IsZero() {
+			return ErrScheduleRecurrence
+		}
+	}
+	return nil
+}
+
+// firstRun returns the first run of the schedule after the time.
+func firstRun(s models.Schedule, now time.Time) string {
+	if s.RunAt != "" {
+		return s.RunAt
+	}
+	spec, err := parseCron(s.Cron)
+	if err != nil {
+		return ""
+	}
+	return formatTime(spec.next(now))
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(timeLayout)
+}
+
+func (c *Calculator) AddSchedule(userID int, opts ScheduleOptions) (models.Schedule, error) {
+	if err := c.checkSchedule(userID, &opts); err != nil {
+		return models.Schedule{}, err
+	}
+	now := time.Now()
+	s := models.Schedule{UserID: userID, Expression: opts.Expression, Priority: opts.Priority, RunAt: formatTime(opts.RunAt), Cron: opts.Cron,
+		CatchUp: opts.CatchUp, Status: scheduleActive, Created: now.Format(timeLayout)}
+	s.NextRun = firstRun(s, now)
+	id, err := c.db.AddSchedule(s)
+	if err != nil {
+		return s, err
+	}
+	s.ID = id
+	return s, nil
+}
+
+func (c *Calculator) GetSchedules(userID int) ([]models.Schedule, error) {
+	return c.db.GetSchedules(userID)
+}
+
+// ownSchedule returns the schedule if the user may change it. Admins may
+// change any schedule.
+func (c *Calculator) ownSchedule(scheduleID, userID int) (models.Schedule, error) {
+	s, err := c.db.GetSchedule(scheduleID)
+	if err != nil {
+		return s, err
+	}
+	if s.UserID != userID {
+		admin, err := c.db.IsAdmin(userID)
+		if err != nil {
+			return s, err
+		}
+		if !admin {
+			return s, storage.ErrScheduleNotFound
+		}
+	}
+	return s, nil
+}
+
+// UpdateSchedule replaces what the schedule computes and when. A finished
+// one-time schedule becomes active again.
+func (c *Calculator) UpdateSchedule(scheduleID, userID int, opts ScheduleOptions) (models.Schedule, error) {
+	s, err := c.ownSchedule(scheduleID, userID)
+	if err != nil {
+		return s, err
+	}
+	if err := c.checkSchedule(s.UserID, &opts); err != nil {
+		return s, err
+	}
+	s.Expression, s.Priority, s.RunAt, s.Cron, s.CatchUp = opts.Expression, opts.Priority, formatTime(opts.RunAt), opts.Cron, opts.CatchUp
+	if s.Status == scheduleDone {
+		s.Status = scheduleActive
+	}
+	s.NextRun = firstRun(s, time.Now())
+	return s, c.db.UpdateSchedule(s)
+}
+
+func (c *Calculator) PauseSchedule(scheduleID, userID int) error {
+	s, err := c.ownSchedule(scheduleID, userID)
+	if err != nil {
+		return err
+	}
+	if s.Status != scheduleActive {
+		return ErrScheduleNotActive
+	}
+	s.Status = schedulePaused
+	return c.db.UpdateSchedule(s)
+}
+
+// ResumeSchedule activates the paused schedule. A recurring schedule does not
+// make up for the runs it had while paused.
+func (c *Calculator) ResumeSchedule(scheduleID, userID int) error {
+	s, err := c.ownSchedule(scheduleID, userID)
+	if err != nil {
+		return err
+	}
+	if s.Status != schedulePaused {
+		return ErrScheduleNotPaused
+	}
+	s.Status = scheduleActive
+	s.NextRun = firstRun(s, time.Now())
+	return c.db.UpdateSchedule(s)
+}
+
+func (c *Calculator) DeleteSchedule(scheduleID, userID int) error {
+	if _, err := c.ownSchedule(scheduleID, userID); err != nil {
+		return err
+	}
+	return c.db.DeleteSchedule(scheduleID)
+}
+
+// runSchedules creates the tasks of the schedules that are due.
+func (c *Calculator) runSchedules() {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopping:
+			return
+		case <-ticker.C:
+		}
+		due, err := c.db.GetDueSchedules(time.Now())
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		for _, s := range due {
+			c.fire(s, time.Now())
+		}
+	}
+}
+
+// fire makes the due runs of the schedule according to its catch-up policy
+// and moves it to its next run.
+func (c *Calculator) fire(s models.Schedule, now time.Time) {
+	due, err := time.ParseInLocation(timeLayout, s.NextRun, time.Local)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	// runs are the times the schedule should have run at up to now.
+	runs := []time.Time{due}
+	next := time.Time{}
+	if s.Cron != "" {
+		spec, err := parseCron(s.Cron)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		next = spec.next(due)
+		for !next.IsZero() && !next.After(now) && len(runs) < maxCatchUp {
+			runs = append(runs, next)
+			next = spec.next(next)
+		}
+		if !next.IsZero() && !next.After(now) {
+			next = spec.next(now)
+		}
+	}
+	count := 1
+	switch s.CatchUp {
+	case catchUpSkip:
+		if now.Sub(runs[len(runs)-1]) > missedAfter {
+			count = 0
+		}
+	case catchUpAll:
+		count = len(runs)
+	}
+	s.Note = ""
+	if missed := len(runs) - count; missed > 0 {
+		s.Note = fmt.Sprintf("%d missed runs skipped", missed)
+	}
+	for i := 0; i < count; i++ {
+		task, err := c.NewTask(s.UserID, s.Expression, TaskOptions{Priority: s.Priority, By: fmt.Sprintf("schedule %d", s.ID)})
+		if err != nil {
+			s.Note = err.Error()
+			break
+		}
+		go task.Start()
+		s.LastTaskID = task.Id
+		s.LastRun = now.Format(timeLayout)
+	}
+	s.NextRun = formatTime(next)
+	if s.NextRun == "" {
+		s.Status = scheduleDone
+	}
+	if err := c.db.UpdateSchedule(s); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,53 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// drainInterval is how often Shutdown checks whether the subtasks being
+// computed are finished.
+const drainInterval = 100 * time.Millisecond
+
+var ErrShuttingDown = errors.New("server is shutting down")
+
+func (c *Calculator) isStopping() bool {
+	select {
+	case <-c.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// drained reports whether no subtask is being computed and all reported
+// results are written. A result is counted as being reported before its lease
+// is released and as unsaved before it stops being reported, so checking in
+// this order never misses it.
+func (c *Calculator) drained() bool {
+	return c.leases.count() == 0 && c.reporting.Load() == 0 && c.tasks.unsaved.Load() == 0
+}
+
+// Shutdown stops taking new tasks and dispatching queued subtasks and waits
+// until the subtasks being computed are finished and their results written or
+// ctx is done. Running tasks keep their status and last step, so they continue
+// after a restart. No steps are written after Shutdown returns.
+func (c *Calculator) Shutdown(ctx context.Context) error {
+	c.stopOnce.Do(func() { close(c.stopping) })
+	c.toProcess.stop()
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+	for !c.drained() {
+		select {
+		case <-ctx.Done():
+			n := c.leases.count()
+			c.tasks.steps.Lock()
+			return fmt.Errorf("%d subtasks interrupted: %w", n, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	c.tasks.steps.Lock()
+	return nil
+}
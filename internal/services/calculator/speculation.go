@@ -0,0 +1,97 @@
+package calculator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/config"
+)
+
+type speculator struct {
+	cfg config.SpeculationConfig
+}
+
+func newSpeculator(cfg config.SpeculationConfig) *speculator {
+	if cfg.Factor < 1 {
+		cfg.Factor = 2
+	}
+	if cfg.Slack <= 0 {
+		cfg.Slack = 2 * time.Second
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	return &speculator{cfg: cfg}
+}
+
+// expected is how long a subtask whose operation takes the delay on average
+// may run before it counts as a straggler.
+func (s *speculator) expected(delay time.Duration) time.Duration {
+	return time.Duration(s.cfg.Factor*float64(delay)) + s.cfg.Slack
+}
+
+func (c *Calculator) speculate() {
+	ticker := time.NewTicker(c.speculator.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopping:
+			return
+		case <-ticker.C:
+		}
+		stragglers := c.leases.stragglers(func(s *Subtask) time.Duration {
+			return c.speculator.expected(meanDelay(c.delayOf(s)))
+		})
+		for _, v := range stragglers {
+			if !c.freeHolder(c.toProcess.route(v.subtask.substack.op), v.holder) || !c.leases.speculate(v) {
+				continue
+			}
+			// The copy shares the state of the subtask, see Subtask.
+			dup := *v.subtask
+			dup.avoid = v.holder
+			c.note(v.subtask, fmt.Sprintf("worker %d is slow (%s), speculative copy queued", v.holder, time.Since(v.started).Round(time.Second)))
+			c.toProcess.push(&dup)
+		}
+	}
+}
+
+// freeHolder reports whether an idle local worker or an online agent with a
+// free slot other than the straggling holder could take a speculative copy
+// queued in the pool. Agents and, with overflow, the shared workers take
+// subtasks of the shared pool only.
+func (c *Calculator) freeHolder(p *pool, straggler int) bool {
+	if c.Worker.idleWorker(p.name) != 0 {
+		return true
+	}
+	if p.overflow && c.Worker.idleWorker(sharedPool) != 0 {
+		return true
+	}
+	if p.name != sharedPool && !p.overflow {
+		return false
+	}
+	c.agents.mu.Lock()
+	defer c.agents.mu.Unlock()
+	for _, a := range c.agents.list {
+		if a.id != straggler && time.Since(a.lastSeen) <= c.agents.offlineAfter && len(c.leases.held(a.id)) < a.slots {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Calculator) GetSchedulerStats() (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"pools":   c.poolsInfo(),
+		"waiting": c.toProcess.waitingGroups(),
+		"quorum":  c.leases.quorum.info(),
+		"tasks": map[string]interface{}{
+			"maxDuration": c.deadlines.get().String(),
+		},
+		"speculation": map[string]interface{}{
+			"enabled": c.speculator.cfg.Enabled,
+			"factor":  c.speculator.cfg.Factor,
+			"slack":   c.speculator.cfg.Slack.String(),
+			"stats":   c.leases.speculationStats(),
+		},
+	}, nil
+}
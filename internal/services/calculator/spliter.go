@@ -4,6 +4,8 @@ import (
 	"regexp"
 	"strings"
 	"unicode"
+
+	"github.com/apple5343/golangProjectV2/internal/domain/models"
 )
 
 type Symbol struct {
@@ -13,6 +15,8 @@ type Symbol struct {
 	isPriority     bool
 	result         string
 	op             string
+	// timing of the copy of the subtask that computed the result.
+	timing models.StepTiming
 }
 
 type Spliter struct {
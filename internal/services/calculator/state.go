@@ -0,0 +1,82 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/domain/models"
+	storage "github.com/apple5343/golangProjectV2/internal/storage/sqlite"
+	"golang.org/x/exp/slices"
+)
+
+// bySystem marks status changes the calculator makes on its own.
+const bySystem = "system"
+
+var ErrTransition = errors.New("status change is not allowed")
+
+// transitions are the statuses a task may move to from each status. A task
+// is queued until a worker takes its first subtask. Finished tasks do not
+// change.
+var transitions = map[string][]string{
+	models.StatusQueued: {models.StatusProcessing, models.StatusPaused, models.StatusFailed,
+		models.StatusCancelled, models.StatusTimedOut},
+	models.StatusProcessing: {models.StatusBlocked, models.StatusPaused, models.StatusCompleted,
+		models.StatusFailed, models.StatusCancelled, models.StatusTimedOut},
+	models.StatusBlocked: {models.StatusProcessing, models.StatusPaused, models.StatusCompleted,
+		models.StatusFailed, models.StatusCancelled, models.StatusTimedOut},
+	// The round a task was paused in may still complete it.
+	models.StatusPaused: {models.StatusProcessing, models.StatusCompleted, models.StatusFailed,
+		models.StatusCancelled, models.StatusTimedOut},
+}
+
+// reopenings are the transitions out of a finished status. They are only
+// allowed to callers that expect the task in that status: a task failed by a
+// dead letter runs again when the dead letter is retried.
+var reopenings = map[string][]string{
+	models.StatusFailed: {models.StatusProcessing},
+}
+
+func canTransition(from, to string) bool {
+	return slices.Contains(transitions[from], to)
+}
+
+func byUser(userID int) string {
+	return fmt.Sprintf("user %d", userID)
+}
+
+func byWorker(id int) string {
+	return fmt.Sprintf("worker %d", id)
+}
+
+// setStatus moves the task to the status if the transition is allowed and
+// records who or what made the change. If from is given, the task must also
+// be in one of those statuses, and a finished task may be reopened from them.
+func setStatus(db storage.SqlDB, taskID int, to, by string, from ...string) error {
+	current, err := db.ChangeStatus(taskID, to, by, time.Now(), func(current string) bool {
+		if len(from) == 0 {
+			return canTransition(current, to)
+		}
+		return slices.Contains(from, current) && (canTransition(current, to) || slices.Contains(reopenings[current], to))
+	})
+	if errors.Is(err, storage.ErrStatusNotAllowed) {
+		return fmt.Errorf("%w: %s to %s", ErrTransition, current, to)
+	}
+	return err
+}
+
+// markStarted moves a queued task to processing when a worker or an agent
+// takes its first subtask.
+func (t *Task) markStarted(holder int) {
+	if t.started.Swap(true) {
+		return
+	}
+	err := setStatus(t.db, t.Id, models.StatusProcessing, byWorker(holder), models.StatusQueued)
+	if err != nil {
+		if !errors.Is(err, ErrTransition) {
+			fmt.Println(err)
+		}
+		return
+	}
+	t.UpdateCh <- TaskUpdate{t.UserID, t.Id, time.Now().Format("2006-01-02 15:04:05"), 0, "", models.StatusProcessing}
+}
@@ -0,0 +1,58 @@
+package calculator
+
+import (
+	"time"
+
+	storage "github.com/apple5343/golangProjectV2/internal/storage/sqlite"
+)
+
+// timeline sums up how long the computed steps of a task waited in the queue
+// and how long they were computed, in milliseconds. Steps dispatched together
+// form a round, which lasts until its last step finishes. These last steps
+// make up the critical path of the task.
+func timeline(steps []map[string]interface{}) map[string]interface{} {
+	type round struct {
+		wait, compute time.Duration
+		finished      time.Time
+	}
+	rounds := map[string]*round{}
+	var wait, compute time.Duration
+	for _, v := range steps {
+		dispatched := stringValue(v["dispatched"])
+		d, err1 := time.Parse(storage.StepTimeLayout, dispatched)
+		s, err2 := time.Parse(storage.StepTimeLayout, stringValue(v["started"]))
+		f, err3 := time.Parse(storage.StepTimeLayout, stringValue(v["finished"]))
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		wait += s.Sub(d)
+		compute += f.Sub(s)
+		r, ok := rounds[dispatched]
+		if !ok {
+			r = &round{}
+			rounds[dispatched] = r
+		}
+		if f.After(r.finished) {
+			r.wait, r.compute, r.finished = s.Sub(d), f.Sub(s), f
+		}
+	}
+	var pathWait, pathCompute time.Duration
+	for _, r := range rounds {
+		pathWait += r.wait
+		pathCompute += r.compute
+	}
+	return map[string]interface{}{
+		"rounds":      len(rounds),
+		"queueWaitMs": wait.Milliseconds(),
+		"computeMs":   compute.Milliseconds(),
+		"criticalPath": map[string]interface{}{
+			"queueWaitMs": pathWait.Milliseconds(),
+			"computeMs":   pathCompute.Milliseconds(),
+		},
+	}
+}
+
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
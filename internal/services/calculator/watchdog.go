@@ -0,0 +1,299 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/app/websocket"
+	"github.com/apple5343/golangProjectV2/internal/config"
+	"github.com/apple5343/golangProjectV2/internal/domain/models"
+)
+
+const (
+	// maxStalls is how many stalls are kept for the admins.
+	maxStalls = 100
+
+	byWatchdog = "watchdog"
+
+	stallActive    = "stalled"
+	stallRecovered = "recovered"
+	// stallEnded means the task stopped running while it was stalled
+	// without being completed: it was paused, cancelled or failed.
+	stallEnded = "ended"
+)
+
+// Stall is a task that made no progress for longer than its threshold.
+type Stall struct {
+	TaskID     int    `json:"taskId"`
+	UserID     int    `json:"userId"`
+	State      string `json:"state"`
+	Step       string `json:"step"`
+	StalledAt  string `json:"stalledAt"`
+	LastPing   string `json:"lastPing"`
+	Threshold  string `json:"threshold"`
+	Attempts   int    `json:"attempts"`
+	ResolvedAt string `json:"resolvedAt,omitempty"`
+	// redispatched is when the subtasks were last queued again.
+	redispatched time.Time
+}
+
+type watchdog struct {
+	mu     sync.Mutex
+	cfg    config.WatchdogConfig
+	stalls []*Stall
+}
+
+func newWatchdog(cfg config.WatchdogConfig) *watchdog {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.Factor < 1 {
+		cfg.Factor = 3
+	}
+	if cfg.Slack <= 0 {
+		cfg.Slack = 30 * time.Second
+	}
+	return &watchdog{cfg: cfg}
+}
+
+// threshold is how long a task whose longest pending operation takes the
+// delay on average may go without a computed subtask.
+func (w *watchdog) threshold(delay time.Duration) time.Duration {
+	return time.Duration(w.cfg.Factor*float64(delay)) + w.cfg.Slack
+}
+
+// active returns the unresolved stall of the task or nil. w.mu must be held.
+func (w *watchdog) active(taskID int) *Stall {
+	for _, v := range w.stalls {
+		if v.TaskID == taskID && v.State == stallActive {
+			return v
+		}
+	}
+	return nil
+}
+
+// add records a stall, the oldest stalls are forgotten. w.mu must be held.
+func (w *watchdog) add(s *Stall) {
+	w.stalls = append(w.stalls, s)
+	if len(w.stalls) > maxStalls {
+		w.stalls = w.stalls[len(w.stalls)-maxStalls:]
+	}
+}
+
+// setCurrent remembers the subtasks of the round the task computes.
+func (t *tasks) setCurrent(task *Task, current []*Subtask) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	task.current = current
+}
+
+// pending returns the subtasks of the current round of the task that are not
+// computed yet. It returns false if the task is not running here.
+func (t *tasks) pending(id int) ([]*Subtask, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	task, ok := t.list[id]
+	if !ok {
+		return nil, false
+	}
+	result := []*Subtask{}
+	for _, v := range task.current {
+		if !v.finished.Load() {
+			result = append(result, v)
+		}
+	}
+	return result, true
+}
+
+// watch looks for processing tasks that have not computed a subtask for
+// longer than expected. Such a task is blocked, the pending subtasks of its
+// round are queued again and the admins are alerted. The task goes back to
+// processing once a subtask result arrives.
+func (c *Calculator) watch() {
+	ticker := time.NewTicker(c.watchdog.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopping:
+			return
+		case <-ticker.C:
+		}
+		running, err := c.db.GetRunningTasks()
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		c.checkStalls(running, time.Now())
+	}
+}
+
+// stallUpdates are the messages about stalls, sent once the watchdog is
+// unlocked so a slow receiver does not hold it.
+type stallUpdates struct {
+	tasks  []TaskUpdate
+	alerts []websocket.Event
+}
+
+func (u *stallUpdates) alert(s *Stall, now time.Time) {
+	update := websocket.TaskStalledMessage(s.TaskID, s.UserID, s.State, now.Format("2006-01-02 15:04:05"), s.LastPing, s.Threshold, s.Attempts)
+	u.alerts = append(u.alerts, *update)
+}
+
+func (c *Calculator) checkStalls(running []models.TaskInfo, now time.Time) {
+	u := c.findStalls(running, now)
+	for _, v := range u.tasks {
+		c.UpdatesTask <- v
+	}
+	for _, v := range u.alerts {
+		c.Updates <- v
+	}
+}
+
+// findStalls blocks the stalled tasks and follows the known stalls. It
+// returns the updates to send.
+func (c *Calculator) findStalls(running []models.TaskInfo, now time.Time) *stallUpdates {
+	c.watchdog.mu.Lock()
+	defer c.watchdog.mu.Unlock()
+	u := &stallUpdates{}
+	seen := make(map[int]bool)
+	for _, v := range running {
+		seen[v.ID] = true
+		if s := c.watchdog.active(v.ID); s != nil {
+			c.followStall(s, v, now, u)
+			continue
+		}
+		if v.Status == models.StatusBlocked {
+			// Blocked before a restart, the resumed round is queued again
+			// anyway.
+			c.watchdog.add(&Stall{TaskID: v.ID, UserID: v.UserID, State: stallActive, Step: v.LastStep, StalledAt: now.Format("2006-01-02 15:04:05"),
+				LastPing: v.LastPing, redispatched: now})
+			continue
+		}
+		pending, threshold, ok := c.stalled(v, now)
+		if !ok {
+			continue
+		}
+		if err := setStatus(c.db, v.ID, models.StatusBlocked, byWatchdog, models.StatusProcessing); err != nil {
+			if !errors.Is(err, ErrTransition) {
+				fmt.Println(err)
+			}
+			continue
+		}
+		s := &Stall{TaskID: v.ID, UserID: v.UserID, State: stallActive, Step: v.LastStep, StalledAt: now.Format("2006-01-02 15:04:05"),
+			LastPing: v.LastPing, Threshold: threshold.String()}
+		c.watchdog.add(s)
+		c.redispatch(s, pending, now)
+		u.tasks = append(u.tasks, TaskUpdate{v.UserID, v.ID, v.LastPing, 0, "", models.StatusBlocked})
+		u.alert(s, now)
+	}
+	for _, v := range c.watchdog.stalls {
+		if v.State != stallActive || seen[v.TaskID] {
+			continue
+		}
+		_, status, err := c.db.GetTaskOwner(v.TaskID)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		v.State, v.ResolvedAt = stallEnded, now.Format("2006-01-02 15:04:05")
+		if status == models.StatusCompleted {
+			v.State = stallRecovered
+			u.alert(v, now)
+		}
+	}
+	return u
+}
+
+// stalled returns the pending subtasks of a processing task that has made no
+// progress for longer than its threshold. A task whose subtasks wait for a
+// worker is not stalled.
+func (c *Calculator) stalled(v models.TaskInfo, now time.Time) ([]*Subtask, time.Duration, bool) {
+	if v.Status != models.StatusProcessing {
+		return nil, 0, false
+	}
+	pending, ok := c.tasks.pending(v.ID)
+	if !ok || len(pending) == 0 {
+		return nil, 0, false
+	}
+	delay := time.Duration(0)
+	for _, s := range pending {
+		if c.toProcess.has(s.key) {
+			return nil, 0, false
+		}
+		delay = max(delay, meanDelay(c.delayOf(s)))
+	}
+	since := v.LastPing
+	if since == "" {
+		since = v.Created
+	}
+	last, err := time.ParseInLocation("2006-01-02 15:04:05", since, time.Local)
+	if err != nil {
+		fmt.Println(err)
+		return nil, 0, false
+	}
+	threshold := c.watchdog.threshold(delay)
+	return pending, threshold, now.Sub(last) > threshold
+}
+
+// followStall unblocks the task once it computed a subtask, or queues its
+// pending subtasks again if it is still stalled after another threshold.
+func (c *Calculator) followStall(s *Stall, v models.TaskInfo, now time.Time, u *stallUpdates) {
+	if v.LastPing != s.LastPing {
+		s.State, s.ResolvedAt, s.LastPing = stallRecovered, now.Format("2006-01-02 15:04:05"), v.LastPing
+		if err := setStatus(c.db, v.ID, models.StatusProcessing, byWatchdog, models.StatusBlocked); err != nil {
+			if !errors.Is(err, ErrTransition) {
+				fmt.Println(err)
+			}
+		} else {
+			u.tasks = append(u.tasks, TaskUpdate{v.UserID, v.ID, v.LastPing, 0, "", models.StatusProcessing})
+		}
+		u.alert(s, now)
+		return
+	}
+	pending, ok := c.tasks.pending(v.ID)
+	if !ok {
+		return
+	}
+	delay := time.Duration(0)
+	for _, p := range pending {
+		delay = max(delay, meanDelay(c.delayOf(p)))
+	}
+	threshold := c.watchdog.threshold(delay)
+	s.Threshold = threshold.String()
+	if now.Sub(s.redispatched) > threshold {
+		c.redispatch(s, pending, now)
+	}
+}
+
+// redispatch queues a copy of every pending subtask that is not waiting in
+// the queue already. The copy that finishes first completes the subtask.
+func (c *Calculator) redispatch(s *Stall, pending []*Subtask, now time.Time) {
+	s.Attempts++
+	s.redispatched = now
+	since := s.LastPing
+	if since == "" {
+		since = "the start"
+	}
+	for _, v := range pending {
+		if v.finished.Load() || c.toProcess.has(v.key) {
+			continue
+		}
+		dup := *v
+		dup.avoid = 0
+		c.note(v, fmt.Sprintf("task stalled, no progress since %s, subtask queued again", since))
+		c.toProcess.push(&dup)
+	}
+}
+
+// GetStalledTasks returns the recent stalls, the latest first.
+func (c *Calculator) GetStalledTasks() []Stall {
+	c.watchdog.mu.Lock()
+	defer c.watchdog.mu.Unlock()
+	result := make([]Stall, 0, len(c.watchdog.stalls))
+	for i := len(c.watchdog.stalls) - 1; i >= 0; i-- {
+		result = append(result, *c.watchdog.stalls[i])
+	}
+	return result
+}
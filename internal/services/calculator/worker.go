@@ -0,0 +1,476 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/app/websocket"
+	"github.com/apple5343/golangProjectV2/internal/lib/eval"
+)
+
+const (
+	defaultWorkersCount = 4
+	deadWorkersLimit    = 10
+
+	eventWorkerAdded = "added"
+)
+
+// workerState is the state of a built-in worker. Remote agents report the
+// same states plus offline.
+type workerState string
+
+const (
+	stateIdle        workerState = "idle"
+	stateBusy        workerState = "busy"
+	stateDraining    workerState = "draining"
+	stateDead        workerState = "dead"
+	stateOffline     workerState = "offline"
+	stateQuarantined workerState = "quarantined"
+)
+
+var (
+	ErrWorkerNotFound = errors.New("worker not found")
+	ErrLastWorker     = errors.New("cannot stop the last worker")
+	ErrNotQuarantined = errors.New("worker is not quarantined")
+	ErrWorkersCount   = errors.New("workers count must be positive")
+)
+
+// worker is a built-in worker goroutine and its statistics. Stopped workers
+// stay in the registry as dead, so their statistics remain visible.
+type worker struct {
+	id           int
+	pool         string
+	state        workerState
+	kill         chan int
+	drain        chan int
+	expression   string
+	expressionId int
+	started      time.Time
+	stopped      time.Time
+	busySince    time.Time
+	busy         time.Duration
+	completed    int
+	lastError    string
+	// crashes counts the subtasks that panicked on the worker.
+	crashes int
+	// lifted wakes the worker once its quarantine is lifted.
+	lifted chan int
+}
+
+// stats returns the statistics of the worker. w.mu of the registry must be held.
+func (v *worker) stats() websocket.WorkerStats {
+	now := time.Now()
+	if v.state == stateDead {
+		now = v.stopped
+	}
+	busy := v.busy
+	if !v.busySince.IsZero() {
+		busy += now.Sub(v.busySince)
+	}
+	utilization := 0.0
+	if alive := now.Sub(v.started); alive > 0 {
+		utilization = math.Round(float64(busy)/float64(alive)*1000) / 10
+	}
+	return websocket.WorkerStats{
+		Started:     v.started.Format("2006-01-02 15:04:05"),
+		Completed:   v.completed,
+		BusyTime:    busy.Round(time.Millisecond).String(),
+		Utilization: utilization,
+		LastError:   v.lastError,
+		Crashes:     v.crashes,
+	}
+}
+
+type Worker struct {
+	mu        sync.Mutex
+	list      []*worker
+	lastId    int
+	delays    *delays
+	toProcess *queue
+	leases    *leases
+	report    func(key, holder int, result string) bool
+	fail      func(key, holder int, reason string) bool
+	Updates   chan websocket.Event
+}
+
+// find returns the worker with the id or nil. w.mu must be held.
+func (w *Worker) find(id int) *worker {
+	for _, v := range w.list {
+		if v.id == id {
+			return v
+		}
+	}
+	return nil
+}
+
+// update changes the worker under the lock and sends its new state to the
+// admins.
+func (w *Worker) update(id int, change func(v *worker)) {
+	if event := w.apply(id, change); event != nil {
+		w.Updates <- *event
+	}
+}
+
+// apply changes the worker under the lock and returns its new state, or nil
+// if there is no such worker. The lock is released even if change panics.
+func (w *Worker) apply(id int, change func(v *worker)) *websocket.Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	v := w.find(id)
+	if v == nil {
+		return nil
+	}
+	change(v)
+	stats := v.stats()
+	return websocket.UpdateWorkerMessage(id, string(v.state), v.expression, v.expressionId, &stats)
+}
+
+// startSubtask marks the worker busy with the subtask.
+func (w *Worker) startSubtask(id int, s *Subtask) {
+	w.update(id, func(v *worker) {
+		if v.state != stateDraining {
+			v.state = stateBusy
+		}
+		v.expression, v.expressionId = s.substack.value, s.taskId
+		v.busySince = time.Now()
+	})
+}
+
+// endSubtask adds the time spent on the subtask to the worker statistics.
+// errMsg is empty if the worker computed the subtask.
+func (w *Worker) endSubtask(id int, errMsg string) {
+	w.update(id, func(v *worker) {
+		if v.state == stateBusy {
+			v.state = stateIdle
+		}
+		if !v.busySince.IsZero() {
+			v.busy += time.Since(v.busySince)
+			v.busySince = time.Time{}
+		}
+		if errMsg == "" {
+			v.completed++
+		} else {
+			v.lastError = errMsg
+		}
+		v.expression, v.expressionId = "", 0
+	})
+}
+
+// crashed counts a subtask that panicked on the worker.
+func (w *Worker) crashed(id int) {
+	w.update(id, func(v *worker) {
+		v.crashes++
+	})
+}
+
+// quarantine marks the worker quarantined. It takes no more subtasks.
+func (w *Worker) quarantine(id int) {
+	w.update(id, func(v *worker) {
+		if v.state != stateDraining {
+			v.state = stateQuarantined
+		}
+	})
+}
+
+// lift marks the quarantined worker idle again.
+func (w *Worker) lift(id int) {
+	w.update(id, func(v *worker) {
+		if v.state == stateQuarantined {
+			v.state = stateIdle
+		}
+	})
+}
+
+// wake tells the worker that its quarantine has been lifted.
+func (w *Worker) wake(id int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if v := w.find(id); v != nil {
+		select {
+		case v.lifted <- id:
+		default:
+		}
+	}
+}
+
+// stop marks the worker dead and forgets the oldest dead workers.
+func (w *Worker) stop(id int) {
+	w.update(id, func(v *worker) {
+		if v.state == stateDead {
+			return
+		}
+		if !v.busySince.IsZero() {
+			v.busy += time.Since(v.busySince)
+			v.busySince = time.Time{}
+		}
+		v.state = stateDead
+		v.stopped = time.Now()
+		v.expression, v.expressionId = "", 0
+		dead := 0
+		for i := len(w.list) - 1; i >= 0; i-- {
+			if w.list[i].state != stateDead {
+				continue
+			}
+			dead++
+			if dead > deadWorkersLimit {
+				w.list = append(w.list[:i], w.list[i+1:]...)
+			}
+		}
+	})
+}
+
+func (w *Worker) GetWorkersInfo() []map[string]interface{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	result := []map[string]interface{}{}
+	for _, v := range w.list {
+		result = append(result, map[string]interface{}{
+			"id":           v.id,
+			"agent":        builtinAgent,
+			"pool":         v.pool,
+			"status":       v.state,
+			"expression":   v.expression,
+			"expressionId": v.expressionId,
+			"stats":        v.stats(),
+		})
+	}
+	return result
+}
+
+// newId returns the next id. Remote agents take their ids from the same
+// sequence, so an id is never shared by a worker and an agent.
+func (w *Worker) newId() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastId++
+	return w.lastId
+}
+
+// AddWorker starts a new worker goroutine in the pool and returns its id.
+func (w *Worker) AddWorker(pool string) int {
+	id := w.newId()
+	v := &worker{id: id, pool: pool, state: stateIdle, kill: make(chan int), drain: make(chan int), lifted: make(chan int, 1), started: time.Now()}
+	w.mu.Lock()
+	w.list = append(w.list, v)
+	stats := v.stats()
+	w.mu.Unlock()
+	go w.run(id, pool, v.kill, v.drain, v.lifted)
+	w.Updates <- *websocket.UpdateWorkerMessage(id, eventWorkerAdded, "", 0, &stats)
+	return id
+}
+
+// RemoveWorker stops the worker at once. A subtask it was computing goes back
+// to the queue.
+func (w *Worker) RemoveWorker(id int) error {
+	w.mu.Lock()
+	v, err := w.stoppable(id)
+	if err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	close(v.kill)
+	w.mu.Unlock()
+	w.stop(id)
+	return nil
+}
+
+// DrainWorker lets the worker finish its current subtask and then stops it.
+func (w *Worker) DrainWorker(id int) error {
+	w.mu.Lock()
+	v, err := w.stoppable(id)
+	if err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	v.state = stateDraining
+	close(v.drain)
+	stats := v.stats()
+	event := websocket.UpdateWorkerMessage(id, string(v.state), v.expression, v.expressionId, &stats)
+	w.mu.Unlock()
+	w.Updates <- *event
+	return nil
+}
+
+// stoppable returns the worker if it can be stopped without leaving the queue
+// of its pool unattended. Quarantined workers take no subtasks, so they do not
+// attend the queue. w.mu must be held.
+func (w *Worker) stoppable(id int) (*worker, error) {
+	found := w.find(id)
+	if found == nil || found.state == stateDraining || found.state == stateDead {
+		return nil, ErrWorkerNotFound
+	}
+	if found.state == stateQuarantined {
+		return found, nil
+	}
+	active := 0
+	for _, v := range w.list {
+		if v.pool == found.pool && v.state != stateDraining && v.state != stateDead && v.state != stateQuarantined {
+			active++
+		}
+	}
+	if active == 1 {
+		return nil, ErrLastWorker
+	}
+	return found, nil
+}
+
+// counts returns the number of workers of the pool that are neither draining,
+// dead nor quarantined and how many of them are idle.
+func (w *Worker) counts(pool string) (int, int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	active, idle := 0, 0
+	for _, v := range w.list {
+		if v.pool != pool {
+			continue
+		}
+		switch v.state {
+		case stateDraining, stateDead, stateQuarantined:
+			continue
+		case stateIdle:
+			idle++
+		}
+		active++
+	}
+	return active, idle
+}
+
+// active returns the ids of the workers of the pool that are neither
+// draining, dead nor quarantined.
+func (w *Worker) active(pool string) []int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	result := []int{}
+	for _, v := range w.list {
+		if v.pool == pool && v.state != stateDraining && v.state != stateDead && v.state != stateQuarantined {
+			result = append(result, v.id)
+		}
+	}
+	return result
+}
+
+// idleWorker returns the id of the newest idle worker of the pool or 0 if all
+// of them are busy.
+func (w *Worker) idleWorker(pool string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i := len(w.list) - 1; i >= 0; i-- {
+		if w.list[i].pool == pool && w.list[i].state == stateIdle {
+			return w.list[i].id
+		}
+	}
+	return 0
+}
+
+func isClosed(ch <-chan int) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *Worker) run(id int, pool string, killCh, drainCh, liftCh <-chan int) {
+	for {
+		if isClosed(drainCh) {
+			w.stop(id)
+			return
+		}
+		var ch <-chan *Subtask
+		if w.leases.quorum.isQuarantined(id) {
+			w.quarantine(id)
+		} else {
+			ch = w.toProcess.ch(pool)
+		}
+		select {
+		case v := <-ch:
+			if w.leases.quorum.isQuarantined(id) {
+				w.toProcess.push(v)
+				continue
+			}
+			if !w.leases.take(w.toProcess, v, id) {
+				continue
+			}
+			if !w.safeCompute(v, id, killCh) {
+				return
+			}
+		case <-liftCh:
+			w.lift(id)
+		case <-drainCh:
+		case <-killCh:
+			return
+		}
+	}
+}
+
+// safeCompute draws the delay of the subtask and computes it. A panic turns
+// into a failed attempt at the subtask, so the worker keeps running. No lock
+// of the worker is held when it is recovered.
+func (w *Worker) safeCompute(v *Subtask, id int, killCh <-chan int) (alive bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			reason := fmt.Sprintf("panic: %v", r)
+			log.Printf("worker %d: subtask %s: %s\n%s", id, v.substack.value, reason, debug.Stack())
+			w.fail(v.key, id, reason)
+			w.endSubtask(id, reason)
+			w.crashed(id)
+			alive = true
+		}
+	}()
+	d, _ := w.delays.forUser(v.userId, v.delayVersion, v.substack.op)
+	delay := sampleDelay(d)
+	v.taken(id, builtinAgent, delay)
+	return w.compute(v, id, delay, killCh)
+}
+
+// compute waits for the operation delay while keeping the lease of the
+// subtask alive and then reports the result. It returns false if the worker
+// was killed in the meantime.
+func (w *Worker) compute(v *Subtask, id int, delay time.Duration, killCh <-chan int) bool {
+	w.startSubtask(id, v)
+	heartbeat := time.NewTicker(w.leases.heartbeatInterval())
+	defer heartbeat.Stop()
+	done := time.After(delay)
+	for {
+		select {
+		case <-done:
+			result, err := eval.Eval(v.substack.value)
+			if err != nil {
+				w.fail(v.key, id, err.Error())
+				w.endSubtask(id, err.Error())
+				var evalErr *eval.Error
+				if errors.As(err, &evalErr) && evalErr.Stage == eval.StagePanic {
+					w.crashed(id)
+				}
+				return true
+			}
+			if !w.report(v.key, id, result) {
+				w.endSubtask(id, ErrLeaseLost.Error())
+				return true
+			}
+			w.endSubtask(id, "")
+			return true
+		case <-heartbeat.C:
+			if !w.leases.heartbeat(v.key, id) {
+				w.endSubtask(id, ErrLeaseLost.Error())
+				return true
+			}
+		case <-v.cancelled:
+			w.leases.drop(v.key, id)
+			v.abandon()
+			w.endSubtask(id, ErrTaskCancelled.Error())
+			return true
+		case <-killCh:
+			if _, ok := w.leases.drop(v.key, id); ok {
+				w.toProcess.push(v)
+			}
+			return false
+		}
+	}
+}
@@ -1,10 +1,14 @@
 window.onload = function(){
     document.querySelector(".expression-btn").addEventListener("click", sendExpression)
     document.querySelector("#exit").addEventListener("click", Exit)
-    document.querySelector(".operations-btn").addEventListener("click", saveDelays)
+    document.querySelector(".operations-btn").addEventListener("click", () => saveDelays(""))
+    document.querySelector(".profile-apply-btn").addEventListener("click", applyProfile)
+    document.querySelector("#delay-mode").addEventListener("change", setDelayMode)
+    document.querySelector(".profile-save-btn").addEventListener("click", () => saveDelays(document.querySelector("#profile-name").value))
     showInfo()
     showTasks()
     showWorkers()
+    showScaling()
     showDelays()
     if (window["WebSocket"]){
         conn = new WebSocket("ws://" + document.location.host + "/ws")
@@ -48,19 +52,54 @@ function WebSocketUpdates(update){
         UpdateTask(JSON.parse(update["message"]))
     } else if (update["type"] == "update worker"){
         UpdateWorker(JSON.parse(update["message"]))
+    } else if (update["type"] == "scaling"){
+        AddScalingDecision(JSON.parse(update["message"]))
+    } else if (update["type"] == "queue"){
+        ShowQueue(JSON.parse(update["message"]))
+    } else if (update["type"] == "task stalled"){
+        const stall = JSON.parse(update["message"])
+        AddStall(stall)
+        showNotification(`Задача ${stall["taskId"]}: ${stall["state"] == "stalled" ? "нет прогресса" : "восстановлена"}`)
     }
 }
 
 function UpdateWorker(info){
     const el = workersList.querySelector('[data-id="' + info["id"] + '"]')
+    if (info["state"] == "added"){
+        if (!el){
+            workersList.prepend(CreateWorker({"id": info["id"], "status": "idle", "expression": "", "stats": info["stats"]}))
+        }
+        return
+    }
+    if (!el){
+        return
+    }
     const infoEl = el.querySelector(".worker-info")
-    if (info["state"] == "at work"){
-        infoEl.innerHTML = `<p>Статус: at work</p>
+    if (info["exp"] != ""){
+        infoEl.innerHTML = `<p>Статус: ${info["state"]}</p>
                             <p>Подзадача: `+info['exp']+`</p>
                             <p>ID задачи: `+info['taskId']+`</p>`
     } else{
-        infoEl.innerHTML = `<p>Статус: in waiting</p>`
+        infoEl.innerHTML = `<p>Статус: ${info["state"]}</p>`
+    }
+    if (info["stats"]){
+        infoEl.insertAdjacentHTML("beforeend", WorkerStats(info["stats"]))
+    }
+}
+
+function WorkerStats(stats){
+    let result = `<p class="worker-stats">Выполнено: ${stats["completed"]}`
+    if (stats["busyTime"]){
+        result += `, занят ${stats["busyTime"]} (${stats["utilization"]}%)`
     }
+    result += `, с ${stats["started"]}</p>`
+    if (stats["crashes"]){
+        result += `<p class="worker-stats">Сбоев: ${stats["crashes"]}</p>`
+    }
+    if (stats["lastError"]){
+        result += `<p class="worker-stats">Последняя ошибка: ${stats["lastError"]}</p>`
+    }
+    return result
 }
 
 function UpdateTask(task){
@@ -68,11 +107,50 @@ function UpdateTask(task){
     const ping = el.querySelector(".lastPing")
     ping.innerText = "Последнее обновление: " + task["lastPing"]
     if (task["isDone"] === 1){
-        el.classList.remove("processing")
+        el.classList.remove("queued", "processing", "blocked")
         el.classList.add("completed")
         const exp = el.querySelector(".expression-value")
         exp.innerText += "="+task["result"]
     }
+    if (task["status"]){
+        el.classList.remove("queued", "processing", "blocked", "paused")
+        el.classList.add(task["status"])
+        setControls(el.querySelector(".controls"), task["taskId"], task["status"])
+    }
+}
+
+// setControls shows the actions available for a task in the status.
+function setControls(controls, id, status){
+    controls.innerHTML = ""
+    const running = [["/pauseTask", "Пауза"], ["/cancelTask", "Отменить"]]
+    const actions = {"queued": running, "processing": running, "blocked": running,
+        "paused": [["/resumeTask", "Продолжить"], ["/cancelTask", "Отменить"]]}
+    for (const [path, text] of actions[status] || []){
+        const action = document.createElement("p")
+        action.classList.add("task-action")
+        action.dataset.id = id
+        action.dataset.path = path
+        action.innerText = text
+        action.addEventListener("click", changeTask)
+        controls.append(action)
+    }
+}
+
+function changeTask(){
+    fetch(window.location.origin + this.dataset.path,{
+        body: JSON.stringify({"id": +this.dataset.id}),
+        method: "POST",
+        headers:{
+            "Content-Type": "application/json"
+        }
+    }).then(response => {
+        if (!response.ok) {
+            return response.text().then(text => Promise.reject(text));
+        }
+    })
+    .catch(error => {
+        showNotification(error);
+    });
 }
 
 async function getWorkers() {
@@ -93,15 +171,90 @@ async function getWorkers() {
 async function showDelays() {
     try {
         const data = await getDelays();
-        for (let i in data){
-            document.getElementById(i).value = data[i]
+        for (let i in data["delays"]){
+            const delay = data["delays"][i]
+            document.getElementById(i).value = delay["kind"] == "fixed" ? (delay["value"] || 0) / 1000 : ""
+            document.querySelector(`.delay-kind[data-op="${i}"]`).textContent = describeDelay(delay)
         }
+        const select = document.querySelector("#delay-profile")
+        select.innerHTML = ""
+        for (let name in data["profiles"]){
+            const option = document.createElement("option")
+            option.value = name
+            option.textContent = name
+            option.selected = name == data["profile"]
+            select.append(option)
+        }
+        document.querySelector("#delay-mode").value = data["mode"]
+        document.querySelector(".delay-version").textContent = `Версия задержек ${data["version"]}`
+        showDelayHistory()
+        showDelayOverrides()
     } catch (error) {
-        const btn = document.querySelector("#settings-btn")
-        btn.disabled = true
+        showUserDelays()
+    }
+}
+
+// showUserDelays shows a user who is not an admin the delays their tasks run
+// with.
+async function showUserDelays() {
+    const response = await fetch(window.location.origin + "/getUserDelays", {
+        method: "GET",
+    });
+    if (!response.ok) {
+        document.querySelector("#settings-btn").disabled = true
+        return
+    }
+    const data = await response.json()
+    const sources = {"user": "ваша", "group": "группы", "global": "общая"}
+    for (let i in data["delays"]){
+        const delay = data["delays"][i]
+        const input = document.getElementById(i)
+        input.value = delay["kind"] == "fixed" ? (delay["value"] || 0) / 1000 : ""
+        input.disabled = true
+        document.querySelector(`.delay-kind[data-op="${i}"]`).textContent = `${describeDelay(delay)} (${sources[data["sources"][i]]})`
+    }
+    if (data["group"]) {
+        document.querySelector(".delay-group").textContent = `Группа ${data["group"]}`
+    }
+    document.querySelector(".delay-admin").classList.add("hide")
+}
+
+async function showDelayOverrides() {
+    const response = await fetch(window.location.origin + "/getDelayOverrides", {
+        method: "GET",
+    });
+    if (!response.ok) {
+        return
+    }
+    const data = await response.json()
+    const list = document.querySelector(".delay-overrides")
+    list.innerHTML = ""
+    const describe = delays => Object.entries(delays).map(([op, delay]) => `${op}: ${describeDelay(delay) || (delay["value"] || 0) + " мс"}`).join(", ")
+    for (let id in data["users"]) {
+        const li = document.createElement("li")
+        li.textContent = `Пользователь ${id}: ${describe(data["users"][id])}`
+        list.append(li)
+    }
+    for (let group in data["groups"]) {
+        const members = Object.keys(data["members"]).filter(id => data["members"][id] == group)
+        const li = document.createElement("li")
+        li.textContent = `Группа ${group} (${members.join(", ")}): ${describe(data["groups"][group])}`
+        list.append(li)
     }
 }
 
+function describeDelay(delay) {
+    switch (delay["kind"]) {
+        case "uniform":
+            return `равномерно ${delay["min"] || 0}–${delay["max"] || 0} мс`
+        case "normal":
+            return `нормально ${delay["mean"]}±${delay["stdDev"] || 0} мс, от ${delay["min"] || 0} мс`
+        case "exponential":
+            return `экспоненциально, в среднем ${delay["mean"]} мс, от ${delay["min"] || 0} мс`
+    }
+    return ""
+}
+
 async function getDelays() {
     try {
         const response = await fetch(window.location.origin + "/getDelays", {
@@ -118,13 +271,58 @@ async function getDelays() {
     }
 }
 
-function saveDelays(){
+function saveDelays(profile){
     const operations = {}
     for (const i of document.querySelectorAll(".operation")){
-        operations[i.id] = +i.value
+        if (i.value !== ""){
+            operations[i.id] = +i.value
+        }
+    }
+    updateDelays({"delays": operations, "profile": profile})
+}
+
+async function showDelayHistory() {
+    const response = await fetch(window.location.origin + "/getDelayHistory", {
+        method: "GET",
+    });
+    if (!response.ok) {
+        return
     }
+    const list = document.querySelector(".delay-history")
+    list.innerHTML = ""
+    for (const i of await response.json()) {
+        const li = document.createElement("li")
+        const profile = i["profile"] ? `, профиль ${i["profile"]}` : ""
+        li.textContent = `${i["id"]}: ${i["created"]}, ${i["author"]}${profile}`
+        list.append(li)
+    }
+}
+
+function setDelayMode(){
+    fetch(window.location.origin + "/setDelayMode",{
+        body: JSON.stringify({"mode": document.querySelector("#delay-mode").value}),
+        method: "POST",
+        headers:{
+            "Content-Type": "application/json"
+        }
+    }).then(response => {
+        if (!response.ok) {
+            return response.text().then(text => Promise.reject(text));
+        }
+        showNotification("Обновлено");
+    })
+    .catch(error => {
+        showNotification(error);
+    });
+}
+
+function applyProfile(){
+    updateDelays({"profile": document.querySelector("#delay-profile").value})
+}
+
+function updateDelays(body){
     fetch(window.location.origin + "/updateDelays",{
-        body: JSON.stringify({"delays": operations}),
+        body: JSON.stringify(body),
         method: "POST",
         headers:{
             "Content-Type": "application/json"
@@ -137,7 +335,7 @@ function saveDelays(){
     })
     .then(data => {
         showNotification("Обновлено");
-        delays = data;
+        showDelays()
     })
     .catch(error => {
         showNotification(error);
@@ -156,6 +354,139 @@ async function showWorkers() {
     }
 }
 
+async function showScaling() {
+    try {
+        const response = await fetch(window.location.origin + "/getScalingInfo", {
+            method: "GET",
+        });
+        if (!response.ok) {
+            return
+        }
+        const data = await response.json()
+        const scaling = document.querySelector(".scaling")
+        scaling.innerHTML = `<p>Автомасштабирование: ${data["enabled"] ? "включено" : "выключено"}, воркеров ${data["min"]}-${data["max"]}</p>
+                             <ul class="scaling-list"></ul>`
+        for (const i of data["decisions"]) {
+            AddScalingDecision(i)
+        }
+        showPools()
+        showQueue()
+        showStalled()
+        showDeadLetters()
+    } catch (error) {
+        showNotification(error)
+    }
+}
+
+async function showPools() {
+    const response = await fetch(window.location.origin + "/getSchedulerStats", {
+        method: "GET",
+    });
+    if (!response.ok) {
+        return
+    }
+    const data = await response.json()
+    const scaling = document.querySelector(".scaling")
+    for (const i of data["pools"]) {
+        scaling.insertAdjacentHTML("afterbegin", `<p>Пул ${i["name"]} (${i["operations"].join(", ")}): воркеров ${i["workers"]}, свободно ${i["idle"]}, в очереди ${i["queueDepth"]}, ожидание ${i["oldestWait"]}</p>`)
+    }
+}
+
+function AddScalingDecision(decision){
+    const list = document.querySelector(".scaling-list")
+    if (!list){
+        return
+    }
+    const li = document.createElement("li")
+    li.innerText = `${decision["time"]} ${decision["action"]} → ${decision["workers"]}: ${decision["reason"]}`
+    list.prepend(li)
+}
+
+async function showQueue() {
+    const response = await fetch(window.location.origin + "/getQueue", {
+        method: "GET",
+    });
+    if (!response.ok) {
+        return
+    }
+    ShowQueue(await response.json())
+}
+
+function ShowQueue(queue){
+    const el = document.querySelector(".queue-inspector")
+    const depth = Object.entries(queue["depth"]).map(([op, count]) => `${op} ${count}`).join(", ")
+    el.innerHTML = `<p>Очередь: ${queue["total"]} (${depth}), самая старая ждёт ${queue["oldestWait"]}</p>
+                    <ul class="queue-list"></ul>`
+    const list = el.querySelector(".queue-list")
+    for (const i of queue["subtasks"]) {
+        const li = document.createElement("li")
+        li.innerText = `${i["next"] ? "→ " : ""}${i["expression"]} (${i["operation"]}, пул ${i["pool"]}): задача ${i["taskId"]}, пользователь ${i["userId"]}, ${i["priority"]}, ждёт ${i["wait"]}`
+        list.append(li)
+    }
+    if (queue["truncated"]) {
+        const li = document.createElement("li")
+        li.innerText = `и ещё ${queue["total"] - queue["subtasks"].length}`
+        list.append(li)
+    }
+}
+
+async function showStalled() {
+    const response = await fetch(window.location.origin + "/getStalledTasks", {
+        method: "GET",
+    });
+    if (!response.ok) {
+        return
+    }
+    const data = await response.json()
+    for (const i of data.reverse()) {
+        AddStall(i)
+    }
+}
+
+function AddStall(stall){
+    const list = document.querySelector(".stalled-list")
+    const li = document.createElement("li")
+    li.innerText = `Задача ${stall["taskId"]} (пользователь ${stall["userId"]}): ${stall["state"]}, последний результат ${stall["lastPing"] || "нет"}, порог ${stall["threshold"]}, повторов ${stall["attempts"]}`
+    list.prepend(li)
+}
+
+async function showDeadLetters() {
+    const response = await fetch(window.location.origin + "/getDeadLetters", {
+        method: "GET",
+    });
+    if (!response.ok) {
+        return
+    }
+    const list = document.querySelector(".dead-letters")
+    list.innerHTML = ""
+    for (const i of await response.json()) {
+        const li = document.createElement("li")
+        li.innerHTML = `<p>Задача ${i["taskId"]}: ${i["value"]}, попыток ${i["attempts"]}, ошибка: ${i["error"]} (${i["created"]})</p>`
+        for (const [path, text] of [["/retryDeadLetter", "Повторить"], ["/discardDeadLetter", "Удалить"]]){
+            const action = document.createElement("p")
+            action.classList.add("task-action")
+            action.innerText = text
+            action.addEventListener("click", () => changeDeadLetter(path, i["id"]))
+            li.append(action)
+        }
+        list.append(li)
+    }
+}
+
+async function changeDeadLetter(path, id){
+    const response = await fetch(window.location.origin + path, {
+        body: JSON.stringify({"id": id}),
+        method: "POST",
+        headers:{
+            "Content-Type": "application/json"
+        }
+    })
+    if (!response.ok) {
+        showNotification(await response.text())
+    }
+    showDeadLetters()
+}
+
 async function showTasks() {
     try {
         const expressionsList = document.querySelector(".expressions-list")
@@ -227,12 +558,14 @@ function showNotification(text) {
 
 function sendExpression(){
     const expression = document.querySelector(".expression-value").value
+    const priority = document.querySelector(".priority").value
+    const maxDuration = document.querySelector(".max-duration").value.trim()
     if (!expression.trim()){
         showNotification("Выражение пустое")
         return
     }
     fetch(window.location.origin + "/addTask",{
-        body: JSON.stringify({"task": expression}),
+        body: JSON.stringify({"task": expression, "priority": priority, "maxDuration": maxDuration}),
         method: "POST",
         headers:{
             "Content-Type": "application/json"
@@ -275,6 +608,10 @@ function CreateExpression(expression) {
     //info.insertAdjacentHTML("beforeend", `<p class="moreinfo" data-id="${expression["id"]}">Больше информации</p>`)
     moreInfo.addEventListener("click", getExpressionInfo)
     info.append(moreInfo)
+    const controls = document.createElement("div")
+    controls.classList.add("controls")
+    setControls(controls, expression["id"], expression["status"])
+    info.append(controls)
     info.classList.add("info")
     div.append(info)
     div.classList.add(expression["status"])
@@ -288,12 +625,20 @@ function CreateWorker(worker) {
     result.classList.add("workers-list-item")
     const div = document.createElement("div")
     div.dataset.id = worker["id"]
-    div.innerHTML = `<p class="worker-name">Worker_${worker["id"]}</p>`
+    div.innerHTML = `<p class="worker-name">Worker_${worker["id"]} (${worker["pool"] || "shared"})</p>`
+    if (worker["remote"]){
+        div.innerHTML = `<p class="worker-name">Агент ${worker["agent"]} (слотов: ${worker["slots"]})</p>`
+    }
     const workerinfo = document.createElement("div")
     workerinfo.classList.add("worker-info")
     workerStatus = document.createElement("p")
     workerStatus.innerHTML = `Статус: ${worker["status"]}`
     workerinfo.append(workerStatus)
+    for (const i of worker["running"] || []){
+        const running = document.createElement("p")
+        running.innerText = `Подзадача: ${i["expression"]} (ID задачи: ${i["expressionId"]})`
+        workerinfo.append(running)
+    }
     if (worker["expression"] != ""){
         workerExpression = document.createElement("p")
         workerExpression.innerText = worker["expression"]
@@ -303,6 +648,9 @@ function CreateWorker(worker) {
         workerinfo.append(workerExpression)
         workerinfo.append(workerExpressionId)
     }
+    if (worker["stats"]){
+        workerinfo.insertAdjacentHTML("beforeend", WorkerStats(worker["stats"]))
+    }
     div.append(workerinfo)
     result.append(div)
     return result
@@ -331,14 +679,32 @@ function showExpressionInfo(task){
         for (const i of task["subtasks"]){
             const li = document.createElement("li")
             li.innerHTML = `<pre>${i["value"]} &rarr; ${i["result"]}            ${i["time"]}</pre>`
+            if (i["agent"]){
+                const time = (s) => Date.parse(s.replace(" ", "T"))
+                const wait = time(i["started"]) - time(i["dispatched"])
+                const compute = time(i["finished"]) - time(i["started"])
+                li.innerHTML += `<pre class="step-timing">${i["agent"]}, воркер ${i["workerId"]}: ожидание ${wait} мс, вычисление ${compute} мс, задержка ${i["delayMs"] || i["delay"] * 1000} мс</pre>`
+            }
+            if (i["note"]){
+                li.innerHTML = `<pre>${i["value"]}: ${i["note"]}            ${i["time"]}</pre>`
+            }
             list.append(li)
         }
     }
+    const history = document.querySelector(".history-list")
+    history.innerHTML = ""
+    for (const i of task["history"] || []){
+        const li = document.createElement("li")
+        li.innerHTML = `<pre>${i["from"] || "—"} &rarr; ${i["to"]}  (${i["by"]})            ${i["time"]}</pre>`
+        history.append(li)
+    }
     content.querySelector(".expression-id").innerText = "ID: " + task["id"]
     content.querySelector(".expression-status").innerText = "Статус: " + task["status"]
     content.querySelector(".expression-lastPing").innerText = "Последнее обновление: " + task["lastPing"]
     content.querySelector(".expression-created").innerText = "Создан: " + task["created"]
     content.querySelector(".expression-result").innerText = "Результат: " + task["result"]
+    const timeline = task["timeline"]
+    content.querySelector(".expression-timeline").innerText = `Критический путь: ожидание ${timeline["criticalPath"]["queueWaitMs"]} мс, вычисление ${timeline["criticalPath"]["computeMs"]} мс (всего ожидание ${timeline["queueWaitMs"]} мс, вычисление ${timeline["computeMs"]} мс)`
     content.querySelector(".expression-info-value").innerText = task["expression"]
 }
 
@@ -2,9 +2,11 @@ package storage
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/apple5343/golangProjectV2/internal/domain/models"
@@ -15,6 +17,12 @@ var (
 	ErrUserExists   = errors.New("user already exists")
 	ErrUserNotFound = errors.New("user not found")
 	ErrTaskNotFound = errors.New("task is not found")
+
+	ErrScheduleNotFound     = errors.New("schedule not found")
+	ErrDeadLetterNotFound   = errors.New("dead letter not found")
+	ErrDelayVersionNotFound = errors.New("delay version not found")
+	ErrStatusNotAllowed     = errors.New("status change is not allowed")
+	ErrJournalEntryNotFound = errors.New("journal entry not found")
 )
 
 type SqlDB struct {
@@ -23,7 +31,9 @@ type SqlDB struct {
 
 func OpenStorage(path string) (*SqlDB, error) {
 	const op = "storage.sqlite.New"
-	database, err := sql.Open("sqlite3", path)
+	// Steps and status changes are written in transactions, so writers wait
+	// for each other instead of failing with "database is locked".
+	database, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -89,7 +99,11 @@ func (s *SqlDB) IsExist(name string) (models.User, error) {
 	return user, nil
 }
 
-func (s *SqlDB) AddTask(task string, userID int, created time.Time) (int, error) {
+func (s *SqlDB) AddTask(task string, userID int, created time.Time, priority string, cost int, deadline time.Time, delayVersion int) (int, error) {
+	var due string
+	if !deadline.IsZero() {
+		due = deadline.Format("2006-01-02 15:04:05")
+	}
 	_, err := s.GetUserInfo(userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -97,12 +111,12 @@ func (s *SqlDB) AddTask(task string, userID int, created time.Time) (int, error)
 		}
 		return 0, err
 	}
-	statement, err := s.db.Prepare("INSERT INTO tasks (expression, status, result, created, lastPing, lastStep, userID) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	statement, err := s.db.Prepare("INSERT INTO tasks (expression, status, result, created, lastPing, lastStep, userID, priority, cost, deadline, delayVersion) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return 0, err
 	}
 	defer statement.Close()
-	res, err := statement.Exec(task, "processing", "", created.Format("2006-01-02 15:04:05"), "", task, userID)
+	res, err := statement.Exec(task, models.StatusQueued, "", created.Format("2006-01-02 15:04:05"), "", task, userID, priority, cost, due, delayVersion)
 	if err != nil {
 		return 0, err
 	}
@@ -123,14 +137,58 @@ func (s *SqlDB) SetResult(taskId int, result string) error {
 	return nil
 }
 
-func (s *SqlDB) SetStatus(taskId int, newStatus string) error {
-	statement, err := s.db.Prepare("UPDATE tasks SET status = ? WHERE id = ?")
-	defer statement.Close()
-	_, err = statement.Exec(newStatus, taskId)
+// ChangeStatus sets the status of the task if allowed accepts the current
+// one and records the change in the status history. It returns the previous
+// status.
+func (s *SqlDB) ChangeStatus(taskID int, status, by string, tim time.Time, allowed func(from string) bool) (string, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return err
+		return "", err
 	}
-	return nil
+	defer tx.Rollback()
+	var from string
+	err = tx.QueryRow("SELECT status FROM tasks WHERE id = ?", taskID).Scan(&from)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrTaskNotFound
+		}
+		return "", err
+	}
+	if !allowed(from) {
+		return from, ErrStatusNotAllowed
+	}
+	if _, err := tx.Exec("UPDATE tasks SET status = ? WHERE id = ?", status, taskID); err != nil {
+		return from, err
+	}
+	_, err = tx.Exec("INSERT INTO statusHistory (taskId, fromStatus, toStatus, changedBy, time) VALUES (?, ?, ?, ?, ?)", taskID, from, status, by, tim.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return from, err
+	}
+	return from, tx.Commit()
+}
+
+// AddStatusHistory records the initial status of a new task.
+func (s *SqlDB) AddStatusHistory(taskID int, status, by string, tim time.Time) error {
+	_, err := s.db.Exec("INSERT INTO statusHistory (taskId, fromStatus, toStatus, changedBy, time) VALUES (?, '', ?, ?, ?)", taskID, status, by, tim.Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// GetStatusHistory returns the status changes of the task, oldest first.
+func (s *SqlDB) GetStatusHistory(taskID int) ([]models.StatusChange, error) {
+	result := []models.StatusChange{}
+	rows, err := s.db.Query("SELECT fromStatus, toStatus, changedBy, time FROM statusHistory WHERE taskId = ? ORDER BY id", taskID)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v models.StatusChange
+		if err := rows.Scan(&v.From, &v.To, &v.By, &v.Time); err != nil {
+			return result, err
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
 }
 
 func (s *SqlDB) GetAllTasks(userID int64) ([]map[string]interface{}, error) {
@@ -168,40 +226,274 @@ func (s *SqlDB) GetAllTasks(userID int64) ([]map[string]interface{}, error) {
 	return results, nil
 }
 
-func (s *SqlDB) GetDelays() (map[string]int, error) {
-	result := make(map[string]int)
-	rows, err := s.db.Query("SELECT * FROM delays")
+// GetDelays returns the delay distribution of every operation. Delays saved
+// by older versions are fixed delays in seconds.
+func (s *SqlDB) GetDelays() (map[string]models.Delay, error) {
+	result := make(map[string]models.Delay)
+	rows, err := s.db.Query("SELECT operation, delay, spec FROM delays")
 	if err != nil {
 		return result, err
 	}
 	defer rows.Close()
-	var op string
-	var delay int
 	for rows.Next() {
-		err := rows.Scan(&op, &delay)
+		var op, spec string
+		var delay int
+		if err := rows.Scan(&op, &delay, &spec); err != nil {
+			return result, err
+		}
+		if spec == "" {
+			result[op] = models.Delay{Kind: models.DelayFixed, Value: delay * 1000}
+			continue
+		}
+		var v models.Delay
+		if err := json.Unmarshal([]byte(spec), &v); err != nil {
+			return result, err
+		}
+		result[op] = v
+	}
+	return result, rows.Err()
+}
+
+// UpdateDelays puts the delays in effect and records them as a new version.
+// The delays must hold every operation.
+func (s *SqlDB) UpdateDelays(newDelays map[string]models.Delay, profile, author string, created time.Time) (models.DelayVersion, error) {
+	version := models.DelayVersion{Delays: newDelays, Profile: profile, Author: author, Created: created.Format("2006-01-02 15:04:05")}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return version, err
+	}
+	defer tx.Rollback()
+	for k, v := range newDelays {
+		spec, err := json.Marshal(v)
+		if err != nil {
+			return version, err
+		}
+		if _, err := tx.Exec("UPDATE delays SET spec = ? WHERE operation = ?", string(spec), k); err != nil {
+			return version, err
+		}
+	}
+	spec, err := json.Marshal(newDelays)
+	if err != nil {
+		return version, err
+	}
+	res, err := tx.Exec("INSERT INTO delayVersions (spec, profile, author, created) VALUES (?, ?, ?, ?)", string(spec), profile, author, version.Created)
+	if err != nil {
+		return version, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return version, err
+	}
+	version.ID = int(id)
+	return version, tx.Commit()
+}
+
+const delayVersionColumns = "id, spec, profile, author, created"
+
+func scanDelayVersion(row interface{ Scan(...interface{}) error }) (models.DelayVersion, error) {
+	var v models.DelayVersion
+	var spec string
+	if err := row.Scan(&v.ID, &spec, &v.Profile, &v.Author, &v.Created); err != nil {
+		return v, err
+	}
+	return v, json.Unmarshal([]byte(spec), &v.Delays)
+}
+
+func (s *SqlDB) GetDelayVersion(id int) (models.DelayVersion, error) {
+	v, err := scanDelayVersion(s.db.QueryRow("SELECT "+delayVersionColumns+" FROM delayVersions WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return v, ErrDelayVersionNotFound
+	}
+	return v, err
+}
+
+// GetLatestDelayVersion returns the version in effect.
+func (s *SqlDB) GetLatestDelayVersion() (models.DelayVersion, error) {
+	v, err := scanDelayVersion(s.db.QueryRow("SELECT " + delayVersionColumns + " FROM delayVersions ORDER BY id DESC LIMIT 1"))
+	if err == sql.ErrNoRows {
+		return v, ErrDelayVersionNotFound
+	}
+	return v, err
+}
+
+// GetDelayVersions returns the delay history, the latest version first.
+func (s *SqlDB) GetDelayVersions() ([]models.DelayVersion, error) {
+	result := []models.DelayVersion{}
+	rows, err := s.db.Query("SELECT " + delayVersionColumns + " FROM delayVersions ORDER BY id DESC")
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		v, err := scanDelayVersion(rows)
 		if err != nil {
 			return result, err
 		}
-		result[op] = delay
+		result = append(result, v)
 	}
-	if err = rows.Err(); err != nil {
+	return result, rows.Err()
+}
+
+// Scopes of delay overrides.
+const (
+	overrideUser  = "user"
+	overrideGroup = "group"
+)
+
+// GetDelayOverrides returns the delay overrides of the users and the groups
+// and the group of every user that has one.
+func (s *SqlDB) GetDelayOverrides() (models.DelayOverrides, error) {
+	result := models.DelayOverrides{Users: map[int]map[string]models.Delay{}, Groups: map[string]map[string]models.Delay{}, Members: map[int]string{}}
+	rows, err := s.db.Query("SELECT scope, target, operation, spec FROM delayOverrides")
+	if err != nil {
 		return result, err
 	}
-	return result, nil
+	defer rows.Close()
+	for rows.Next() {
+		var scope, target, op, spec string
+		if err := rows.Scan(&scope, &target, &op, &spec); err != nil {
+			return result, err
+		}
+		var v models.Delay
+		if err := json.Unmarshal([]byte(spec), &v); err != nil {
+			return result, err
+		}
+		switch scope {
+		case overrideUser:
+			id, err := strconv.Atoi(target)
+			if err != nil {
+				return result, err
+			}
+			if result.Users[id] == nil {
+				result.Users[id] = make(map[string]models.Delay)
+			}
+			result.Users[id][op] = v
+		case overrideGroup:
+			if result.Groups[target] == nil {
+				result.Groups[target] = make(map[string]models.Delay)
+			}
+			result.Groups[target][op] = v
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+	members, err := s.db.Query("SELECT id, delayGroup FROM users WHERE delayGroup != ''")
+	if err != nil {
+		return result, err
+	}
+	defer members.Close()
+	for members.Next() {
+		var id int
+		var group string
+		if err := members.Scan(&id, &group); err != nil {
+			return result, err
+		}
+		result.Members[id] = group
+	}
+	return result, members.Err()
 }
 
-func (s *SqlDB) UpdateDelays(newDelays map[string]int) error {
-	for k, v := range newDelays {
-		statement, err := s.db.Prepare("UPDATE delays SET delay = ? WHERE operation = ?")
-		defer statement.Close()
-		_, err = statement.Exec(v, k)
+// SetUserDelays replaces the delay overrides of the user, no delays remove
+// them.
+func (s *SqlDB) SetUserDelays(userID int, delays map[string]models.Delay) error {
+	if _, err := s.GetUserInfo(userID); err != nil {
+		return err
+	}
+	return s.setDelayOverrides(overrideUser, strconv.Itoa(userID), delays)
+}
+
+// SetGroupDelays replaces the delay overrides of the group, no delays remove
+// them.
+func (s *SqlDB) SetGroupDelays(group string, delays map[string]models.Delay) error {
+	return s.setDelayOverrides(overrideGroup, group, delays)
+}
+
+func (s *SqlDB) setDelayOverrides(scope, target string, delays map[string]models.Delay) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM delayOverrides WHERE scope = ? AND target = ?", scope, target); err != nil {
+		return err
+	}
+	for k, v := range delays {
+		spec, err := json.Marshal(v)
 		if err != nil {
 			return err
 		}
+		if _, err := tx.Exec("INSERT INTO delayOverrides (scope, target, operation, spec) VALUES (?, ?, ?, ?)", scope, target, k, string(spec)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetUserDelayGroup puts the user in the delay group, an empty group takes
+// the user out of the group.
+func (s *SqlDB) SetUserDelayGroup(userID int, group string) error {
+	res, err := s.db.Exec("UPDATE users SET delayGroup = ? WHERE id = ?", group, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUserNotFound
 	}
 	return nil
 }
 
+// GetDelayProfiles returns the saved delay profiles by name.
+func (s *SqlDB) GetDelayProfiles() (map[string]map[string]models.Delay, error) {
+	result := make(map[string]map[string]models.Delay)
+	rows, err := s.db.Query("SELECT name, operation, spec FROM delayProfiles")
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, op, spec string
+		if err := rows.Scan(&name, &op, &spec); err != nil {
+			return result, err
+		}
+		var v models.Delay
+		if err := json.Unmarshal([]byte(spec), &v); err != nil {
+			return result, err
+		}
+		if result[name] == nil {
+			result[name] = make(map[string]models.Delay)
+		}
+		result[name][op] = v
+	}
+	return result, rows.Err()
+}
+
+// SaveDelayProfile creates the profile or replaces its delays.
+func (s *SqlDB) SaveDelayProfile(name string, delays map[string]models.Delay) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM delayProfiles WHERE name = ?", name); err != nil {
+		return err
+	}
+	for k, v := range delays {
+		spec, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO delayProfiles (name, operation, spec) VALUES (?, ?, ?)", name, k, string(spec)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 func (s *SqlDB) AddSubtask(value string, tim time.Time, parentId int, updated string) error {
 	statement, err := s.db.Prepare("INSERT INTO subtasks (value, time, parentId, result) VALUES (?, ?, ?, ?)")
 	if err != nil {
@@ -215,6 +507,18 @@ func (s *SqlDB) AddSubtask(value string, tim time.Time, parentId int, updated st
 	return err
 }
 
+// AddStepNote records an event in the step history of the task, e.g. that a
+// subtask was given to another worker.
+func (s *SqlDB) AddStepNote(parentId int, value, note string, tim time.Time) error {
+	statement, err := s.db.Prepare("INSERT INTO subtasks (value, time, parentId, result, note) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+	_, err = statement.Exec(value, tim.Format("2006-01-02 15:04:05"), parentId, "", note)
+	return err
+}
+
 func (s *SqlDB) GetSubtasks(parentId int) ([]map[string]interface{}, error) {
 	var results []map[string]interface{}
 	rows, err := s.db.Query("SELECT * FROM subtasks WHERE parentId = ?", parentId)
@@ -252,10 +556,10 @@ func (s *SqlDB) GetSubtasks(parentId int) ([]map[string]interface{}, error) {
 
 func (s *SqlDB) GetTaskById(taskId, userID int64) (map[string]interface{}, error) {
 	res := make(map[string]interface{})
-	var id, c int
-	var expression, status, result, created, lastPing, lastStep string
-	row := s.db.QueryRow("SELECT * FROM tasks WHERE id = ? AND userID = ?", taskId, userID)
-	err := row.Scan(&id, &expression, &status, &result, &created, &lastPing, &lastStep, &c)
+	var id, c, delayVersion int
+	var expression, status, result, created, lastPing, lastStep, priority, deadline string
+	row := s.db.QueryRow("SELECT id, expression, status, result, created, lastPing, lastStep, userID, priority, deadline, delayVersion FROM tasks WHERE id = ? AND userID = ?", taskId, userID)
+	err := row.Scan(&id, &expression, &status, &result, &created, &lastPing, &lastStep, &c, &priority, &deadline, &delayVersion)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return res, ErrTaskNotFound
@@ -269,11 +573,19 @@ func (s *SqlDB) GetTaskById(taskId, userID int64) (map[string]interface{}, error
 	res["created"] = created
 	res["lastPing"] = lastPing
 	res["lastStep"] = lastStep
+	res["priority"] = priority
+	res["deadline"] = deadline
+	res["delayVersion"] = delayVersion
 	subtasks, err := s.GetSubtasks(int(taskId))
 	if err != nil {
 		return res, err
 	}
 	res["subtasks"] = subtasks
+	history, err := s.GetStatusHistory(int(taskId))
+	if err != nil {
+		return res, err
+	}
+	res["history"] = history
 	return res, nil
 }
 
@@ -289,7 +601,7 @@ func (s *SqlDB) UpdatePing(taskId int, tim time.Time) error {
 
 func (s *SqlDB) GetInterruptedTasks() ([]map[string]interface{}, error) {
 	var results []map[string]interface{}
-	rows, err := s.db.Query("SELECT * FROM tasks WHERE status = ?", "processing")
+	rows, err := s.db.Query("SELECT * FROM tasks WHERE status IN (?, ?, ?)", models.StatusQueued, models.StatusBlocked, models.StatusProcessing)
 	if err != nil {
 		return results, err
 	}
@@ -343,3 +655,373 @@ func (s *SqlDB) GetUserInfo(userID int) (string, error) {
 	}
 	return result, nil
 }
+
+func (s *SqlDB) SetUserWeight(userID, weight int) error {
+	res, err := s.db.Exec("UPDATE users SET weight = ? WHERE id = ?", weight, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// GetUserWeights returns the fair share weights that differ from the default.
+func (s *SqlDB) GetUserWeights() (map[int]int, error) {
+	result := make(map[int]int)
+	rows, err := s.db.Query("SELECT id, weight FROM users WHERE weight != 1")
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, weight int
+		if err := rows.Scan(&id, &weight); err != nil {
+			return result, err
+		}
+		result[id] = weight
+	}
+	return result, rows.Err()
+}
+
+// GetUserQuota returns the quota overrides of the user.
+func (s *SqlDB) GetUserQuota(userID int) (models.Quota, error) {
+	var q models.Quota
+	err := s.db.QueryRow("SELECT maxConcurrent, maxPerHour, maxDelayPerDay FROM users WHERE id = ?", userID).Scan(&q.Concurrent, &q.PerHour, &q.DelayPerDay)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return q, ErrUserNotFound
+		}
+		return q, err
+	}
+	return q, nil
+}
+
+func (s *SqlDB) SetUserQuota(userID int, q models.Quota) error {
+	res, err := s.db.Exec("UPDATE users SET maxConcurrent = ?, maxPerHour = ?, maxDelayPerDay = ? WHERE id = ?", q.Concurrent, q.PerHour, q.DelayPerDay, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *SqlDB) CountRunningTasks(userID int) (int, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE userID = ? AND status IN (?, ?, ?)", userID, models.StatusQueued, models.StatusBlocked, models.StatusProcessing).Scan(&count)
+	return count, err
+}
+
+// GetTasksSince returns the tasks the user created after the time, oldest first.
+func (s *SqlDB) GetTasksSince(userID int, since time.Time) ([]models.TaskUsage, error) {
+	result := []models.TaskUsage{}
+	rows, err := s.db.Query("SELECT created, cost FROM tasks WHERE userID = ? AND created > ? ORDER BY created", userID, since.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v models.TaskUsage
+		if err := rows.Scan(&v.Created, &v.Cost); err != nil {
+			return result, err
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
+}
+
+// GetTaskOwner returns the user and the status of the task.
+func (s *SqlDB) GetTaskOwner(taskID int) (int, string, error) {
+	var userID int
+	var status string
+	err := s.db.QueryRow("SELECT userID, status FROM tasks WHERE id = ?", taskID).Scan(&userID, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, "", ErrTaskNotFound
+		}
+		return 0, "", err
+	}
+	return userID, status, nil
+}
+
+// GetOverdueTasks returns the running and paused tasks whose deadline is
+// before the time.
+func (s *SqlDB) GetOverdueTasks(now time.Time) ([]models.TaskInfo, error) {
+	result := []models.TaskInfo{}
+	rows, err := s.db.Query("SELECT id, userID, status, lastStep, deadline FROM tasks WHERE status IN (?, ?, ?, ?) AND deadline != '' AND deadline <= ?", models.StatusQueued, models.StatusBlocked, models.StatusProcessing, models.StatusPaused, now.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v models.TaskInfo
+		if err := rows.Scan(&v.ID, &v.UserID, &v.Status, &v.LastStep, &v.Deadline); err != nil {
+			return result, err
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
+}
+
+// GetRunningTasks returns the tasks that are being computed.
+func (s *SqlDB) GetRunningTasks() ([]models.TaskInfo, error) {
+	result := []models.TaskInfo{}
+	rows, err := s.db.Query("SELECT id, userID, status, lastStep, deadline, created, lastPing FROM tasks WHERE status IN (?, ?)", models.StatusProcessing, models.StatusBlocked)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v models.TaskInfo
+		if err := rows.Scan(&v.ID, &v.UserID, &v.Status, &v.LastStep, &v.Deadline, &v.Created, &v.LastPing); err != nil {
+			return result, err
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
+}
+
+// GetSetting returns the value of the setting or an empty string if it is
+// not set.
+func (s *SqlDB) GetSetting(name string) (string, error) {
+	var value string
+	err := s.db.QueryRow("SELECT value FROM settings WHERE name = ?", name).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+func (s *SqlDB) SetSetting(name, value string) error {
+	_, err := s.db.Exec("INSERT INTO settings (name, value) VALUES (?, ?) ON CONFLICT(name) DO UPDATE SET value = excluded.value", name, value)
+	return err
+}
+
+const scheduleColumns = "id, userID, expression, priority, runAt, cron, catchUp, status, nextRun, lastRun, lastTaskId, note, created"
+
+func scanSchedule(row interface{ Scan(...interface{}) error }) (models.Schedule, error) {
+	var v models.Schedule
+	err := row.Scan(&v.ID, &v.UserID, &v.Expression, &v.Priority, &v.RunAt, &v.Cron, &v.CatchUp, &v.Status, &v.NextRun, &v.LastRun, &v.LastTaskID, &v.Note, &v.Created)
+	return v, err
+}
+
+func (s *SqlDB) querySchedules(query string, args ...interface{}) ([]models.Schedule, error) {
+	result := []models.Schedule{}
+	rows, err := s.db.Query("SELECT "+scheduleColumns+" FROM schedules "+query, args...)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		v, err := scanSchedule(rows)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
+}
+
+func (s *SqlDB) AddSchedule(v models.Schedule) (int, error) {
+	res, err := s.db.Exec("INSERT INTO schedules (userID, expression, priority, runAt, cron, catchUp, status, nextRun, lastRun, lastTaskId, note, created) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		v.UserID, v.Expression, v.Priority, v.RunAt, v.Cron, v.CatchUp, v.Status, v.NextRun, v.LastRun, v.LastTaskID, v.Note, v.Created)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (s *SqlDB) UpdateSchedule(v models.Schedule) error {
+	res, err := s.db.Exec("UPDATE schedules SET expression = ?, priority = ?, runAt = ?, cron = ?, catchUp = ?, status = ?, nextRun = ?, lastRun = ?, lastTaskId = ?, note = ? WHERE id = ?",
+		v.Expression, v.Priority, v.RunAt, v.Cron, v.CatchUp, v.Status, v.NextRun, v.LastRun, v.LastTaskID, v.Note, v.ID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+func (s *SqlDB) DeleteSchedule(id int) error {
+	_, err := s.db.Exec("DELETE FROM schedules WHERE id = ?", id)
+	return err
+}
+
+func (s *SqlDB) GetSchedule(id int) (models.Schedule, error) {
+	v, err := scanSchedule(s.db.QueryRow("SELECT "+scheduleColumns+" FROM schedules WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return v, ErrScheduleNotFound
+	}
+	return v, err
+}
+
+func (s *SqlDB) GetSchedules(userID int) ([]models.Schedule, error) {
+	return s.querySchedules("WHERE userID = ? ORDER BY id", userID)
+}
+
+// GetDueSchedules returns the active schedules whose next run is not after
+// the time.
+func (s *SqlDB) GetDueSchedules(now time.Time) ([]models.Schedule, error) {
+	return s.querySchedules("WHERE status = ? AND nextRun != '' AND nextRun <= ? ORDER BY nextRun", "active", now.Format("2006-01-02 15:04:05"))
+}
+
+// Close closes the database. Writes that are in progress are finished first.
+func (s *SqlDB) Close() error {
+	return s.db.Close()
+}
+
+// StartRound replaces the journal of the task with the subtasks of a new
+// round.
+func (s *SqlDB) StartRound(taskID int, entries []models.JournalEntry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM journal WHERE taskId = ?", taskID); err != nil {
+		return err
+	}
+	for _, v := range entries {
+		_, err := tx.Exec("INSERT INTO journal (taskId, symbolId, round, value, result, done) VALUES (?, ?, ?, ?, '', 0)", taskID, v.SymbolID, v.Round, v.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// StepTimeLayout is the format of the times of a step, with milliseconds.
+const StepTimeLayout = "2006-01-02 15:04:05.000"
+
+// CompleteSubtask marks the subtask done in the journal and writes the step
+// it makes in the same transaction, so a step is neither lost nor recorded
+// twice. It returns ErrJournalEntryNotFound if the subtask is not journaled
+// or already done.
+func (s *SqlDB) CompleteSubtask(entry models.JournalEntry, timing models.StepTiming, tim time.Time, value, updated, lastStep string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec("UPDATE journal SET result = ?, done = 1 WHERE taskId = ? AND symbolId = ? AND done = 0", entry.Result, entry.TaskID, entry.SymbolID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrJournalEntryNotFound
+	}
+	formatted := tim.Format("2006-01-02 15:04:05")
+	_, err = tx.Exec("INSERT INTO subtasks (value, time, parentId, result, workerId, agent, dispatched, started, finished, delayMs) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		value, formatted, entry.TaskID, updated, timing.WorkerID, timing.Agent, timing.Dispatched.Format(StepTimeLayout),
+		timing.Started.Format(StepTimeLayout), timing.Finished.Format(StepTimeLayout), timing.Delay.Milliseconds())
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE tasks SET lastPing = ?, lastStep = ? WHERE id = ?", formatted, lastStep, entry.TaskID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const deadLetterColumns = "id, taskId, userID, value, operation, attempts, error, created"
+
+func scanDeadLetter(row interface{ Scan(...interface{}) error }) (models.DeadLetter, error) {
+	var v models.DeadLetter
+	err := row.Scan(&v.ID, &v.TaskID, &v.UserID, &v.Value, &v.Operation, &v.Attempts, &v.Error, &v.Created)
+	return v, err
+}
+
+func (s *SqlDB) AddDeadLetter(v models.DeadLetter) (int, error) {
+	res, err := s.db.Exec("INSERT INTO deadLetters (taskId, userID, value, operation, attempts, error, created) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		v.TaskID, v.UserID, v.Value, v.Operation, v.Attempts, v.Error, v.Created)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (s *SqlDB) GetDeadLetter(id int) (models.DeadLetter, error) {
+	v, err := scanDeadLetter(s.db.QueryRow("SELECT "+deadLetterColumns+" FROM deadLetters WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return v, ErrDeadLetterNotFound
+	}
+	return v, err
+}
+
+func (s *SqlDB) GetDeadLetters() ([]models.DeadLetter, error) {
+	result := []models.DeadLetter{}
+	rows, err := s.db.Query("SELECT " + deadLetterColumns + " FROM deadLetters ORDER BY id")
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		v, err := scanDeadLetter(rows)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
+}
+
+func (s *SqlDB) DeleteDeadLetter(id int) error {
+	res, err := s.db.Exec("DELETE FROM deadLetters WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// GetJournal returns the subtasks of the current round of the task.
+func (s *SqlDB) GetJournal(taskID int) ([]models.JournalEntry, error) {
+	rows, err := s.db.Query("SELECT taskId, symbolId, round, value, result, done FROM journal WHERE taskId = ? ORDER BY symbolId", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := []models.JournalEntry{}
+	for rows.Next() {
+		var v models.JournalEntry
+		if err := rows.Scan(&v.TaskID, &v.SymbolID, &v.Round, &v.Value, &v.Result, &v.Done); err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
+}
+
+// ClearJournal forgets the round of a task that is finished or stopped.
+func (s *SqlDB) ClearJournal(taskID int) error {
+	_, err := s.db.Exec("DELETE FROM journal WHERE taskId = ?", taskID)
+	return err
+}
@@ -64,6 +64,211 @@ func createTables(db *sql.DB) error {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	err = addColumn(db, "subtasks", "note", "TEXT DEFAULT ''")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	for _, column := range []string{"workerId", "delay", "delayMs"} {
+		err = addColumn(db, "subtasks", column, "INTEGER DEFAULT 0")
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	for _, column := range []string{"agent", "dispatched", "started", "finished"} {
+		err = addColumn(db, "subtasks", column, "TEXT DEFAULT ''")
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	err = addColumn(db, "tasks", "priority", "TEXT DEFAULT 'normal'")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	err = addColumn(db, "users", "weight", "INTEGER DEFAULT 1")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	err = addColumn(db, "users", "delayGroup", "TEXT DEFAULT ''")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	err = addColumn(db, "tasks", "cost", "INTEGER DEFAULT 0")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	err = addColumn(db, "tasks", "deadline", "TEXT DEFAULT ''")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	err = addColumn(db, "tasks", "delayVersion", "INTEGER DEFAULT 0")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	for _, column := range []string{"maxConcurrent", "maxPerHour", "maxDelayPerDay"} {
+		err = addColumn(db, "users", column, "INTEGER DEFAULT -1")
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	stmt, err = db.Prepare(`
+	CREATE TABLE IF NOT EXISTS 
+	schedules (
+		id	INTEGER,
+		userID INTEGER,
+		expression TEXT,
+		priority TEXT,
+		runAt TEXT,
+		cron TEXT,
+		catchUp TEXT,
+		status TEXT,
+		nextRun TEXT,
+		lastRun TEXT,
+		lastTaskId INTEGER,
+		note TEXT,
+		created TEXT,
+		PRIMARY KEY(id AUTOINCREMENT)
+	);`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err = db.Prepare(`
+	CREATE TABLE IF NOT EXISTS 
+	statusHistory (
+		id	INTEGER,
+		taskId INTEGER,
+		fromStatus TEXT,
+		toStatus TEXT,
+		changedBy TEXT,
+		time TEXT,
+		PRIMARY KEY(id AUTOINCREMENT)
+	);`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err = db.Prepare(`
+	CREATE TABLE IF NOT EXISTS 
+	journal (
+		taskId INTEGER,
+		symbolId INTEGER,
+		round TEXT,
+		value TEXT,
+		result TEXT,
+		done INTEGER,
+		PRIMARY KEY(taskId, symbolId)
+	);`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err = db.Prepare(`
+	CREATE TABLE IF NOT EXISTS 
+	deadLetters (
+		id	INTEGER,
+		taskId INTEGER,
+		userID INTEGER,
+		value TEXT,
+		operation TEXT,
+		attempts INTEGER,
+		error TEXT,
+		created TEXT,
+		PRIMARY KEY(id AUTOINCREMENT)
+	);`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err = db.Prepare(`
+	CREATE TABLE IF NOT EXISTS 
+	settings (
+		name TEXT PRIMARY KEY,
+		value TEXT
+	);`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err = db.Prepare(`
+	CREATE TABLE IF NOT EXISTS 
+	delayProfiles (
+		name TEXT,
+		operation TEXT,
+		spec TEXT,
+		PRIMARY KEY(name, operation)
+	);`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	for name, profile := range defaultProfiles {
+		for operation, spec := range profile {
+			_, err = db.Exec("INSERT OR IGNORE INTO delayProfiles (name, operation, spec) VALUES (?, ?, ?)", name, operation, spec)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	stmt, err = db.Prepare(`
+	CREATE TABLE IF NOT EXISTS 
+	delayOverrides (
+		scope TEXT,
+		target TEXT,
+		operation TEXT,
+		spec TEXT,
+		PRIMARY KEY(scope, target, operation)
+	);`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err = db.Prepare(`
+	CREATE TABLE IF NOT EXISTS 
+	delayVersions (
+		id INTEGER,
+		spec TEXT,
+		profile TEXT,
+		author TEXT,
+		created TEXT,
+		PRIMARY KEY(id AUTOINCREMENT)
+	);`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = stmt.Exec()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	stmt, err = db.Prepare(`
 	CREATE TABLE IF NOT EXISTS 
 	delays (
@@ -77,6 +282,10 @@ func createTables(db *sql.DB) error {
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	err = addColumn(db, "delays", "spec", "TEXT DEFAULT ''")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 	delays := []string{"plus", "minus", "multiplication", "division"}
 	for _, i := range delays {
 		var delay int
@@ -94,3 +303,50 @@ func createTables(db *sql.DB) error {
 	}
 	return nil
 }
+
+// defaultProfiles are the delay profiles every database starts with.
+var defaultProfiles = map[string]map[string]string{
+	"demo": {
+		"plus":           `{"kind":"fixed","value":1000}`,
+		"minus":          `{"kind":"fixed","value":1000}`,
+		"multiplication": `{"kind":"fixed","value":2000}`,
+		"division":       `{"kind":"fixed","value":2000}`,
+	},
+	"realistic": {
+		"plus":           `{"kind":"normal","mean":2000,"stdDev":500,"min":200}`,
+		"minus":          `{"kind":"normal","mean":2000,"stdDev":500,"min":200}`,
+		"multiplication": `{"kind":"normal","mean":4000,"stdDev":1000,"min":500}`,
+		"division":       `{"kind":"exponential","mean":5000,"min":500}`,
+	},
+	"stress": {
+		"plus":           `{"kind":"uniform","min":0,"max":100}`,
+		"minus":          `{"kind":"uniform","min":0,"max":100}`,
+		"multiplication": `{"kind":"uniform","min":0,"max":200}`,
+		"division":       `{"kind":"uniform","min":0,"max":200}`,
+	},
+}
+
+// addColumn adds the column to a table created by an older version.
+func addColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
@@ -217,11 +217,41 @@ var Auth_ServiceDesc = grpc.ServiceDesc{
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type CalculatorClient interface {
 	AddTask(ctx context.Context, in *AddTaskRequest, opts ...grpc.CallOption) (*AddTaskResponse, error)
+	CancelTask(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*Empty, error)
+	PauseTask(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*Empty, error)
+	ResumeTask(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*Empty, error)
+	AddSchedule(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*ScheduleResponse, error)
+	GetSchedules(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*GetSchedulesResponse, error)
+	UpdateSchedule(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*ScheduleResponse, error)
+	PauseSchedule(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*Empty, error)
+	ResumeSchedule(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*Empty, error)
+	DeleteSchedule(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*Empty, error)
 	GetAllTasks(ctx context.Context, in *GetAllTasksRequest, opts ...grpc.CallOption) (*GetAllTasksResponse, error)
 	GetWorkersInfo(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetWorkersInfoResponse, error)
 	UpdateDelays(ctx context.Context, in *UpdateDelaysRequest, opts ...grpc.CallOption) (*Empty, error)
 	GetDelays(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetDelaysResponse, error)
+	GetDelayHistory(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetDelayHistoryResponse, error)
+	SetDelayMode(ctx context.Context, in *SetDelayModeRequest, opts ...grpc.CallOption) (*Empty, error)
+	GetDelayOverrides(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetDelayOverridesResponse, error)
+	SetDelayOverride(ctx context.Context, in *DelayOverrideRequest, opts ...grpc.CallOption) (*Empty, error)
+	SetUserDelayGroup(ctx context.Context, in *SetUserDelayGroupRequest, opts ...grpc.CallOption) (*Empty, error)
+	GetUserDelays(ctx context.Context, in *GetUserDelaysRequest, opts ...grpc.CallOption) (*GetDelaysResponse, error)
 	GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*GetTaskResponse, error)
+	AddWorkers(ctx context.Context, in *AddWorkersRequest, opts ...grpc.CallOption) (*GetWorkersInfoResponse, error)
+	RemoveWorker(ctx context.Context, in *WorkerRequest, opts ...grpc.CallOption) (*Empty, error)
+	DrainWorker(ctx context.Context, in *WorkerRequest, opts ...grpc.CallOption) (*Empty, error)
+	UnquarantineWorker(ctx context.Context, in *WorkerRequest, opts ...grpc.CallOption) (*Empty, error)
+	GetScalingInfo(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetScalingInfoResponse, error)
+	GetSchedulerStats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetSchedulerStatsResponse, error)
+	GetStalledTasks(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetStalledTasksResponse, error)
+	GetQueue(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetQueueResponse, error)
+	GetDeadLetters(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetDeadLettersResponse, error)
+	RetryDeadLetter(ctx context.Context, in *DeadLetterRequest, opts ...grpc.CallOption) (*Empty, error)
+	DiscardDeadLetter(ctx context.Context, in *DeadLetterRequest, opts ...grpc.CallOption) (*Empty, error)
+	SetQuorum(ctx context.Context, in *SetQuorumRequest, opts ...grpc.CallOption) (*Empty, error)
+	SetUserWeight(ctx context.Context, in *SetUserWeightRequest, opts ...grpc.CallOption) (*Empty, error)
+	SetUserQuota(ctx context.Context, in *SetUserQuotaRequest, opts ...grpc.CallOption) (*Empty, error)
+	SetDefaultTimeout(ctx context.Context, in *SetDefaultTimeoutRequest, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type calculatorClient struct {
@@ -241,6 +271,87 @@ func (c *calculatorClient) AddTask(ctx context.Context, in *AddTaskRequest, opts
 	return out, nil
 }
 
+func (c *calculatorClient) CancelTask(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/CancelTask", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) PauseTask(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/PauseTask", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) ResumeTask(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/ResumeTask", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) AddSchedule(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*ScheduleResponse, error) {
+	out := new(ScheduleResponse)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/AddSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) GetSchedules(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*GetSchedulesResponse, error) {
+	out := new(GetSchedulesResponse)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/GetSchedules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) UpdateSchedule(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*ScheduleResponse, error) {
+	out := new(ScheduleResponse)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/UpdateSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) PauseSchedule(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/PauseSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) ResumeSchedule(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/ResumeSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) DeleteSchedule(ctx context.Context, in *ScheduleRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/DeleteSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *calculatorClient) GetAllTasks(ctx context.Context, in *GetAllTasksRequest, opts ...grpc.CallOption) (*GetAllTasksResponse, error) {
 	out := new(GetAllTasksResponse)
 	err := c.cc.Invoke(ctx, "/calc.Calculator/GetAllTasks", in, out, opts...)
@@ -277,6 +388,60 @@ func (c *calculatorClient) GetDelays(ctx context.Context, in *Empty, opts ...grp
 	return out, nil
 }
 
+func (c *calculatorClient) GetDelayHistory(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetDelayHistoryResponse, error) {
+	out := new(GetDelayHistoryResponse)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/GetDelayHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) SetDelayMode(ctx context.Context, in *SetDelayModeRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/SetDelayMode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) GetDelayOverrides(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetDelayOverridesResponse, error) {
+	out := new(GetDelayOverridesResponse)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/GetDelayOverrides", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) SetDelayOverride(ctx context.Context, in *DelayOverrideRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/SetDelayOverride", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) SetUserDelayGroup(ctx context.Context, in *SetUserDelayGroupRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/SetUserDelayGroup", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) GetUserDelays(ctx context.Context, in *GetUserDelaysRequest, opts ...grpc.CallOption) (*GetDelaysResponse, error) {
+	out := new(GetDelaysResponse)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/GetUserDelays", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *calculatorClient) GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*GetTaskResponse, error) {
 	out := new(GetTaskResponse)
 	err := c.cc.Invoke(ctx, "/calc.Calculator/GetTask", in, out, opts...)
@@ -286,16 +451,181 @@ func (c *calculatorClient) GetTask(ctx context.Context, in *GetTaskRequest, opts
 	return out, nil
 }
 
+func (c *calculatorClient) AddWorkers(ctx context.Context, in *AddWorkersRequest, opts ...grpc.CallOption) (*GetWorkersInfoResponse, error) {
+	out := new(GetWorkersInfoResponse)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/AddWorkers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) RemoveWorker(ctx context.Context, in *WorkerRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/RemoveWorker", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) DrainWorker(ctx context.Context, in *WorkerRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/DrainWorker", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) UnquarantineWorker(ctx context.Context, in *WorkerRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/UnquarantineWorker", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) GetScalingInfo(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetScalingInfoResponse, error) {
+	out := new(GetScalingInfoResponse)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/GetScalingInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) GetSchedulerStats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetSchedulerStatsResponse, error) {
+	out := new(GetSchedulerStatsResponse)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/GetSchedulerStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) GetStalledTasks(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetStalledTasksResponse, error) {
+	out := new(GetStalledTasksResponse)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/GetStalledTasks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) GetQueue(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetQueueResponse, error) {
+	out := new(GetQueueResponse)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/GetQueue", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) GetDeadLetters(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetDeadLettersResponse, error) {
+	out := new(GetDeadLettersResponse)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/GetDeadLetters", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) RetryDeadLetter(ctx context.Context, in *DeadLetterRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/RetryDeadLetter", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) DiscardDeadLetter(ctx context.Context, in *DeadLetterRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/DiscardDeadLetter", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) SetQuorum(ctx context.Context, in *SetQuorumRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/SetQuorum", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) SetUserWeight(ctx context.Context, in *SetUserWeightRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/SetUserWeight", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) SetUserQuota(ctx context.Context, in *SetUserQuotaRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/SetUserQuota", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClient) SetDefaultTimeout(ctx context.Context, in *SetDefaultTimeoutRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Calculator/SetDefaultTimeout", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CalculatorServer is the server API for Calculator service.
 // All implementations must embed UnimplementedCalculatorServer
 // for forward compatibility
 type CalculatorServer interface {
 	AddTask(context.Context, *AddTaskRequest) (*AddTaskResponse, error)
+	CancelTask(context.Context, *TaskRequest) (*Empty, error)
+	PauseTask(context.Context, *TaskRequest) (*Empty, error)
+	ResumeTask(context.Context, *TaskRequest) (*Empty, error)
+	AddSchedule(context.Context, *ScheduleRequest) (*ScheduleResponse, error)
+	GetSchedules(context.Context, *ScheduleRequest) (*GetSchedulesResponse, error)
+	UpdateSchedule(context.Context, *ScheduleRequest) (*ScheduleResponse, error)
+	PauseSchedule(context.Context, *ScheduleRequest) (*Empty, error)
+	ResumeSchedule(context.Context, *ScheduleRequest) (*Empty, error)
+	DeleteSchedule(context.Context, *ScheduleRequest) (*Empty, error)
 	GetAllTasks(context.Context, *GetAllTasksRequest) (*GetAllTasksResponse, error)
 	GetWorkersInfo(context.Context, *Empty) (*GetWorkersInfoResponse, error)
 	UpdateDelays(context.Context, *UpdateDelaysRequest) (*Empty, error)
 	GetDelays(context.Context, *Empty) (*GetDelaysResponse, error)
+	GetDelayHistory(context.Context, *Empty) (*GetDelayHistoryResponse, error)
+	SetDelayMode(context.Context, *SetDelayModeRequest) (*Empty, error)
+	GetDelayOverrides(context.Context, *Empty) (*GetDelayOverridesResponse, error)
+	SetDelayOverride(context.Context, *DelayOverrideRequest) (*Empty, error)
+	SetUserDelayGroup(context.Context, *SetUserDelayGroupRequest) (*Empty, error)
+	GetUserDelays(context.Context, *GetUserDelaysRequest) (*GetDelaysResponse, error)
 	GetTask(context.Context, *GetTaskRequest) (*GetTaskResponse, error)
+	AddWorkers(context.Context, *AddWorkersRequest) (*GetWorkersInfoResponse, error)
+	RemoveWorker(context.Context, *WorkerRequest) (*Empty, error)
+	DrainWorker(context.Context, *WorkerRequest) (*Empty, error)
+	UnquarantineWorker(context.Context, *WorkerRequest) (*Empty, error)
+	GetScalingInfo(context.Context, *Empty) (*GetScalingInfoResponse, error)
+	GetSchedulerStats(context.Context, *Empty) (*GetSchedulerStatsResponse, error)
+	GetStalledTasks(context.Context, *Empty) (*GetStalledTasksResponse, error)
+	GetQueue(context.Context, *Empty) (*GetQueueResponse, error)
+	GetDeadLetters(context.Context, *Empty) (*GetDeadLettersResponse, error)
+	RetryDeadLetter(context.Context, *DeadLetterRequest) (*Empty, error)
+	DiscardDeadLetter(context.Context, *DeadLetterRequest) (*Empty, error)
+	SetQuorum(context.Context, *SetQuorumRequest) (*Empty, error)
+	SetUserWeight(context.Context, *SetUserWeightRequest) (*Empty, error)
+	SetUserQuota(context.Context, *SetUserQuotaRequest) (*Empty, error)
+	SetDefaultTimeout(context.Context, *SetDefaultTimeoutRequest) (*Empty, error)
 	mustEmbedUnimplementedCalculatorServer()
 }
 
@@ -306,6 +636,33 @@ type UnimplementedCalculatorServer struct {
 func (UnimplementedCalculatorServer) AddTask(context.Context, *AddTaskRequest) (*AddTaskResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AddTask not implemented")
 }
+func (UnimplementedCalculatorServer) CancelTask(context.Context, *TaskRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelTask not implemented")
+}
+func (UnimplementedCalculatorServer) PauseTask(context.Context, *TaskRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseTask not implemented")
+}
+func (UnimplementedCalculatorServer) ResumeTask(context.Context, *TaskRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeTask not implemented")
+}
+func (UnimplementedCalculatorServer) AddSchedule(context.Context, *ScheduleRequest) (*ScheduleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddSchedule not implemented")
+}
+func (UnimplementedCalculatorServer) GetSchedules(context.Context, *ScheduleRequest) (*GetSchedulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSchedules not implemented")
+}
+func (UnimplementedCalculatorServer) UpdateSchedule(context.Context, *ScheduleRequest) (*ScheduleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateSchedule not implemented")
+}
+func (UnimplementedCalculatorServer) PauseSchedule(context.Context, *ScheduleRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseSchedule not implemented")
+}
+func (UnimplementedCalculatorServer) ResumeSchedule(context.Context, *ScheduleRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeSchedule not implemented")
+}
+func (UnimplementedCalculatorServer) DeleteSchedule(context.Context, *ScheduleRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSchedule not implemented")
+}
 func (UnimplementedCalculatorServer) GetAllTasks(context.Context, *GetAllTasksRequest) (*GetAllTasksResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetAllTasks not implemented")
 }
@@ -318,9 +675,72 @@ func (UnimplementedCalculatorServer) UpdateDelays(context.Context, *UpdateDelays
 func (UnimplementedCalculatorServer) GetDelays(context.Context, *Empty) (*GetDelaysResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetDelays not implemented")
 }
+func (UnimplementedCalculatorServer) GetDelayHistory(context.Context, *Empty) (*GetDelayHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDelayHistory not implemented")
+}
+func (UnimplementedCalculatorServer) SetDelayMode(context.Context, *SetDelayModeRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDelayMode not implemented")
+}
+func (UnimplementedCalculatorServer) GetDelayOverrides(context.Context, *Empty) (*GetDelayOverridesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDelayOverrides not implemented")
+}
+func (UnimplementedCalculatorServer) SetDelayOverride(context.Context, *DelayOverrideRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDelayOverride not implemented")
+}
+func (UnimplementedCalculatorServer) SetUserDelayGroup(context.Context, *SetUserDelayGroupRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetUserDelayGroup not implemented")
+}
+func (UnimplementedCalculatorServer) GetUserDelays(context.Context, *GetUserDelaysRequest) (*GetDelaysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserDelays not implemented")
+}
 func (UnimplementedCalculatorServer) GetTask(context.Context, *GetTaskRequest) (*GetTaskResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetTask not implemented")
 }
+func (UnimplementedCalculatorServer) AddWorkers(context.Context, *AddWorkersRequest) (*GetWorkersInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddWorkers not implemented")
+}
+func (UnimplementedCalculatorServer) RemoveWorker(context.Context, *WorkerRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveWorker not implemented")
+}
+func (UnimplementedCalculatorServer) DrainWorker(context.Context, *WorkerRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DrainWorker not implemented")
+}
+func (UnimplementedCalculatorServer) UnquarantineWorker(context.Context, *WorkerRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnquarantineWorker not implemented")
+}
+func (UnimplementedCalculatorServer) GetScalingInfo(context.Context, *Empty) (*GetScalingInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetScalingInfo not implemented")
+}
+func (UnimplementedCalculatorServer) GetSchedulerStats(context.Context, *Empty) (*GetSchedulerStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSchedulerStats not implemented")
+}
+func (UnimplementedCalculatorServer) GetStalledTasks(context.Context, *Empty) (*GetStalledTasksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStalledTasks not implemented")
+}
+func (UnimplementedCalculatorServer) GetQueue(context.Context, *Empty) (*GetQueueResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQueue not implemented")
+}
+func (UnimplementedCalculatorServer) GetDeadLetters(context.Context, *Empty) (*GetDeadLettersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeadLetters not implemented")
+}
+func (UnimplementedCalculatorServer) RetryDeadLetter(context.Context, *DeadLetterRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RetryDeadLetter not implemented")
+}
+func (UnimplementedCalculatorServer) DiscardDeadLetter(context.Context, *DeadLetterRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DiscardDeadLetter not implemented")
+}
+func (UnimplementedCalculatorServer) SetQuorum(context.Context, *SetQuorumRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetQuorum not implemented")
+}
+func (UnimplementedCalculatorServer) SetUserWeight(context.Context, *SetUserWeightRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetUserWeight not implemented")
+}
+func (UnimplementedCalculatorServer) SetUserQuota(context.Context, *SetUserQuotaRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetUserQuota not implemented")
+}
+func (UnimplementedCalculatorServer) SetDefaultTimeout(context.Context, *SetDefaultTimeoutRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDefaultTimeout not implemented")
+}
 func (UnimplementedCalculatorServer) mustEmbedUnimplementedCalculatorServer() {}
 
 // UnsafeCalculatorServer may be embedded to opt out of forward compatibility for this service.
@@ -352,97 +772,637 @@ func _Calculator_AddTask_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Calculator_GetAllTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetAllTasksRequest)
+func _Calculator_CancelTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CalculatorServer).GetAllTasks(ctx, in)
+		return srv.(CalculatorServer).CancelTask(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/calc.Calculator/GetAllTasks",
+		FullMethod: "/calc.Calculator/CancelTask",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CalculatorServer).GetAllTasks(ctx, req.(*GetAllTasksRequest))
+		return srv.(CalculatorServer).CancelTask(ctx, req.(*TaskRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Calculator_GetWorkersInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
+func _Calculator_PauseTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CalculatorServer).GetWorkersInfo(ctx, in)
+		return srv.(CalculatorServer).PauseTask(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/calc.Calculator/GetWorkersInfo",
+		FullMethod: "/calc.Calculator/PauseTask",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CalculatorServer).GetWorkersInfo(ctx, req.(*Empty))
+		return srv.(CalculatorServer).PauseTask(ctx, req.(*TaskRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Calculator_UpdateDelays_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateDelaysRequest)
+func _Calculator_ResumeTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CalculatorServer).UpdateDelays(ctx, in)
+		return srv.(CalculatorServer).ResumeTask(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/calc.Calculator/UpdateDelays",
+		FullMethod: "/calc.Calculator/ResumeTask",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CalculatorServer).UpdateDelays(ctx, req.(*UpdateDelaysRequest))
+		return srv.(CalculatorServer).ResumeTask(ctx, req.(*TaskRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Calculator_GetDelays_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
+func _Calculator_AddSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduleRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CalculatorServer).GetDelays(ctx, in)
+		return srv.(CalculatorServer).AddSchedule(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/calc.Calculator/GetDelays",
+		FullMethod: "/calc.Calculator/AddSchedule",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CalculatorServer).GetDelays(ctx, req.(*Empty))
+		return srv.(CalculatorServer).AddSchedule(ctx, req.(*ScheduleRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Calculator_GetTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetTaskRequest)
+func _Calculator_GetSchedules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduleRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CalculatorServer).GetTask(ctx, in)
+		return srv.(CalculatorServer).GetSchedules(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/calc.Calculator/GetTask",
+		FullMethod: "/calc.Calculator/GetSchedules",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CalculatorServer).GetTask(ctx, req.(*GetTaskRequest))
+		return srv.(CalculatorServer).GetSchedules(ctx, req.(*ScheduleRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-// Calculator_ServiceDesc is the grpc.ServiceDesc for Calculator service.
+func _Calculator_UpdateSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).UpdateSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/UpdateSchedule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).UpdateSchedule(ctx, req.(*ScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_PauseSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).PauseSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/PauseSchedule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).PauseSchedule(ctx, req.(*ScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_ResumeSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).ResumeSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/ResumeSchedule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).ResumeSchedule(ctx, req.(*ScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_DeleteSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).DeleteSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/DeleteSchedule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).DeleteSchedule(ctx, req.(*ScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_GetAllTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).GetAllTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/GetAllTasks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).GetAllTasks(ctx, req.(*GetAllTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_GetWorkersInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).GetWorkersInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/GetWorkersInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).GetWorkersInfo(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_UpdateDelays_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateDelaysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).UpdateDelays(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/UpdateDelays",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).UpdateDelays(ctx, req.(*UpdateDelaysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_GetDelays_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).GetDelays(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/GetDelays",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).GetDelays(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_GetDelayHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).GetDelayHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/GetDelayHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).GetDelayHistory(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_SetDelayMode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDelayModeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).SetDelayMode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/SetDelayMode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).SetDelayMode(ctx, req.(*SetDelayModeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_GetDelayOverrides_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).GetDelayOverrides(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/GetDelayOverrides",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).GetDelayOverrides(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_SetDelayOverride_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DelayOverrideRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).SetDelayOverride(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/SetDelayOverride",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).SetDelayOverride(ctx, req.(*DelayOverrideRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_SetUserDelayGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetUserDelayGroupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).SetUserDelayGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/SetUserDelayGroup",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).SetUserDelayGroup(ctx, req.(*SetUserDelayGroupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_GetUserDelays_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserDelaysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).GetUserDelays(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/GetUserDelays",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).GetUserDelays(ctx, req.(*GetUserDelaysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_GetTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).GetTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/GetTask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).GetTask(ctx, req.(*GetTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_AddWorkers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddWorkersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).AddWorkers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/AddWorkers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).AddWorkers(ctx, req.(*AddWorkersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_RemoveWorker_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).RemoveWorker(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/RemoveWorker",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).RemoveWorker(ctx, req.(*WorkerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_DrainWorker_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).DrainWorker(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/DrainWorker",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).DrainWorker(ctx, req.(*WorkerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_UnquarantineWorker_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).UnquarantineWorker(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/UnquarantineWorker",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).UnquarantineWorker(ctx, req.(*WorkerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_GetScalingInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).GetScalingInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/GetScalingInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).GetScalingInfo(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_GetSchedulerStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).GetSchedulerStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/GetSchedulerStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).GetSchedulerStats(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_GetStalledTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).GetStalledTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/GetStalledTasks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).GetStalledTasks(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_GetQueue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).GetQueue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/GetQueue",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).GetQueue(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_GetDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).GetDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/GetDeadLetters",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).GetDeadLetters(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_RetryDeadLetter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeadLetterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).RetryDeadLetter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/RetryDeadLetter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).RetryDeadLetter(ctx, req.(*DeadLetterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_DiscardDeadLetter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeadLetterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).DiscardDeadLetter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/DiscardDeadLetter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).DiscardDeadLetter(ctx, req.(*DeadLetterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_SetQuorum_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetQuorumRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).SetQuorum(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/SetQuorum",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).SetQuorum(ctx, req.(*SetQuorumRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_SetUserWeight_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetUserWeightRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).SetUserWeight(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/SetUserWeight",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).SetUserWeight(ctx, req.(*SetUserWeightRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_SetUserQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetUserQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).SetUserQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/SetUserQuota",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).SetUserQuota(ctx, req.(*SetUserQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Calculator_SetDefaultTimeout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDefaultTimeoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServer).SetDefaultTimeout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Calculator/SetDefaultTimeout",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServer).SetDefaultTimeout(ctx, req.(*SetDefaultTimeoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Calculator_ServiceDesc is the grpc.ServiceDesc for Calculator service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var Calculator_ServiceDesc = grpc.ServiceDesc{
@@ -453,6 +1413,42 @@ var Calculator_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AddTask",
 			Handler:    _Calculator_AddTask_Handler,
 		},
+		{
+			MethodName: "CancelTask",
+			Handler:    _Calculator_CancelTask_Handler,
+		},
+		{
+			MethodName: "PauseTask",
+			Handler:    _Calculator_PauseTask_Handler,
+		},
+		{
+			MethodName: "ResumeTask",
+			Handler:    _Calculator_ResumeTask_Handler,
+		},
+		{
+			MethodName: "AddSchedule",
+			Handler:    _Calculator_AddSchedule_Handler,
+		},
+		{
+			MethodName: "GetSchedules",
+			Handler:    _Calculator_GetSchedules_Handler,
+		},
+		{
+			MethodName: "UpdateSchedule",
+			Handler:    _Calculator_UpdateSchedule_Handler,
+		},
+		{
+			MethodName: "PauseSchedule",
+			Handler:    _Calculator_PauseSchedule_Handler,
+		},
+		{
+			MethodName: "ResumeSchedule",
+			Handler:    _Calculator_ResumeSchedule_Handler,
+		},
+		{
+			MethodName: "DeleteSchedule",
+			Handler:    _Calculator_DeleteSchedule_Handler,
+		},
 		{
 			MethodName: "GetAllTasks",
 			Handler:    _Calculator_GetAllTasks_Handler,
@@ -469,10 +1465,288 @@ var Calculator_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetDelays",
 			Handler:    _Calculator_GetDelays_Handler,
 		},
+		{
+			MethodName: "GetDelayHistory",
+			Handler:    _Calculator_GetDelayHistory_Handler,
+		},
+		{
+			MethodName: "SetDelayMode",
+			Handler:    _Calculator_SetDelayMode_Handler,
+		},
+		{
+			MethodName: "GetDelayOverrides",
+			Handler:    _Calculator_GetDelayOverrides_Handler,
+		},
+		{
+			MethodName: "SetDelayOverride",
+			Handler:    _Calculator_SetDelayOverride_Handler,
+		},
+		{
+			MethodName: "SetUserDelayGroup",
+			Handler:    _Calculator_SetUserDelayGroup_Handler,
+		},
+		{
+			MethodName: "GetUserDelays",
+			Handler:    _Calculator_GetUserDelays_Handler,
+		},
 		{
 			MethodName: "GetTask",
 			Handler:    _Calculator_GetTask_Handler,
 		},
+		{
+			MethodName: "AddWorkers",
+			Handler:    _Calculator_AddWorkers_Handler,
+		},
+		{
+			MethodName: "RemoveWorker",
+			Handler:    _Calculator_RemoveWorker_Handler,
+		},
+		{
+			MethodName: "DrainWorker",
+			Handler:    _Calculator_DrainWorker_Handler,
+		},
+		{
+			MethodName: "UnquarantineWorker",
+			Handler:    _Calculator_UnquarantineWorker_Handler,
+		},
+		{
+			MethodName: "GetScalingInfo",
+			Handler:    _Calculator_GetScalingInfo_Handler,
+		},
+		{
+			MethodName: "GetSchedulerStats",
+			Handler:    _Calculator_GetSchedulerStats_Handler,
+		},
+		{
+			MethodName: "GetStalledTasks",
+			Handler:    _Calculator_GetStalledTasks_Handler,
+		},
+		{
+			MethodName: "GetQueue",
+			Handler:    _Calculator_GetQueue_Handler,
+		},
+		{
+			MethodName: "GetDeadLetters",
+			Handler:    _Calculator_GetDeadLetters_Handler,
+		},
+		{
+			MethodName: "RetryDeadLetter",
+			Handler:    _Calculator_RetryDeadLetter_Handler,
+		},
+		{
+			MethodName: "DiscardDeadLetter",
+			Handler:    _Calculator_DiscardDeadLetter_Handler,
+		},
+		{
+			MethodName: "SetQuorum",
+			Handler:    _Calculator_SetQuorum_Handler,
+		},
+		{
+			MethodName: "SetUserWeight",
+			Handler:    _Calculator_SetUserWeight_Handler,
+		},
+		{
+			MethodName: "SetUserQuota",
+			Handler:    _Calculator_SetUserQuota_Handler,
+		},
+		{
+			MethodName: "SetDefaultTimeout",
+			Handler:    _Calculator_SetDefaultTimeout_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/calc.proto",
+}
+
+// AgentClient is the client API for Agent service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AgentClient interface {
+	RegisterAgent(ctx context.Context, in *RegisterAgentRequest, opts ...grpc.CallOption) (*RegisterAgentResponse, error)
+	GetSubtask(ctx context.Context, in *GetSubtaskRequest, opts ...grpc.CallOption) (*GetSubtaskResponse, error)
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+	SendResult(ctx context.Context, in *SendResultRequest, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type agentClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAgentClient(cc grpc.ClientConnInterface) AgentClient {
+	return &agentClient{cc}
+}
+
+func (c *agentClient) RegisterAgent(ctx context.Context, in *RegisterAgentRequest, opts ...grpc.CallOption) (*RegisterAgentResponse, error) {
+	out := new(RegisterAgentResponse)
+	err := c.cc.Invoke(ctx, "/calc.Agent/RegisterAgent", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) GetSubtask(ctx context.Context, in *GetSubtaskRequest, opts ...grpc.CallOption) (*GetSubtaskResponse, error) {
+	out := new(GetSubtaskResponse)
+	err := c.cc.Invoke(ctx, "/calc.Agent/GetSubtask", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	out := new(HeartbeatResponse)
+	err := c.cc.Invoke(ctx, "/calc.Agent/Heartbeat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) SendResult(ctx context.Context, in *SendResultRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/calc.Agent/SendResult", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AgentServer is the server API for Agent service.
+// All implementations must embed UnimplementedAgentServer
+// for forward compatibility
+type AgentServer interface {
+	RegisterAgent(context.Context, *RegisterAgentRequest) (*RegisterAgentResponse, error)
+	GetSubtask(context.Context, *GetSubtaskRequest) (*GetSubtaskResponse, error)
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
+	SendResult(context.Context, *SendResultRequest) (*Empty, error)
+	mustEmbedUnimplementedAgentServer()
+}
+
+// UnimplementedAgentServer must be embedded to have forward compatible implementations.
+type UnimplementedAgentServer struct {
+}
+
+func (UnimplementedAgentServer) RegisterAgent(context.Context, *RegisterAgentRequest) (*RegisterAgentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterAgent not implemented")
+}
+func (UnimplementedAgentServer) GetSubtask(context.Context, *GetSubtaskRequest) (*GetSubtaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSubtask not implemented")
+}
+func (UnimplementedAgentServer) Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedAgentServer) SendResult(context.Context, *SendResultRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendResult not implemented")
+}
+func (UnimplementedAgentServer) mustEmbedUnimplementedAgentServer() {}
+
+// UnsafeAgentServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AgentServer will
+// result in compilation errors.
+type UnsafeAgentServer interface {
+	mustEmbedUnimplementedAgentServer()
+}
+
+func RegisterAgentServer(s grpc.ServiceRegistrar, srv AgentServer) {
+	s.RegisterService(&Agent_ServiceDesc, srv)
+}
+
+func _Agent_RegisterAgent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterAgentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).RegisterAgent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Agent/RegisterAgent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).RegisterAgent(ctx, req.(*RegisterAgentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_GetSubtask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSubtaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).GetSubtask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Agent/GetSubtask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).GetSubtask(ctx, req.(*GetSubtaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Agent/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_SendResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).SendResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calc.Agent/SendResult",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).SendResult(ctx, req.(*SendResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Agent_ServiceDesc is the grpc.ServiceDesc for Agent service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Agent_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "calc.Agent",
+	HandlerType: (*AgentServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RegisterAgent",
+			Handler:    _Agent_RegisterAgent_Handler,
+		},
+		{
+			MethodName: "GetSubtask",
+			Handler:    _Agent_GetSubtask_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _Agent_Heartbeat_Handler,
+		},
+		{
+			MethodName: "SendResult",
+			Handler:    _Agent_SendResult_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/calc.proto",
@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/lib/eval"
+	c "github.com/apple5343/golangProjectV2/proto"
+	"github.com/apple5343/golangProjectV2/tests/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAgents_Register(t *testing.T) {
+	ctx, st := test.New(t)
+
+	tests := []struct {
+		name  string
+		agent string
+		slots int64
+	}{
+		{"empty name", " ", 1},
+		{"no slots", "agent", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AgentClient.RegisterAgent(ctx, &c.RegisterAgentRequest{Name: tt.agent, Slots: tt.slots})
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+
+	name := fmt.Sprintf("agent%d", time.Now().UnixNano())
+	res, err := st.AgentClient.RegisterAgent(ctx, &c.RegisterAgentRequest{Name: name, Slots: 2})
+	require.NoError(t, err)
+	resp, err := st.CalcClient.GetWorkersInfo(ctx, &c.Empty{})
+	require.NoError(t, err)
+	var agent map[string]interface{}
+	for _, w := range workersInfo(t, resp.Workers) {
+		if w["remote"] == true && int64(w["id"].(float64)) == res.AgentId {
+			agent = w
+		}
+	}
+	require.NotNil(t, agent)
+	assert.Equal(t, name, agent["agent"])
+	assert.Equal(t, 2.0, agent["slots"])
+	assert.Equal(t, "idle", agent["status"])
+
+	_, err = st.AgentClient.GetSubtask(ctx, &c.GetSubtaskRequest{AgentId: -1})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = st.AgentClient.SendResult(ctx, &c.SendResultRequest{AgentId: -1, SubtaskId: 1, Result: "1"})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestAgents_SendResult(t *testing.T) {
+	ctx, st := test.New(t)
+
+	req := agentTask(ctx, t, st, "2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3", `{"multiplication": 30}`)
+	agentId := registerAgent(ctx, t, st)
+	subtask := ownSubtask(ctx, t, st, agentId, req.TaskId)
+	assert.Equal(t, "2*3", subtask.Expression)
+	assert.Equal(t, "multiplication", subtask.Operation)
+	assert.Equal(t, int64(30000), subtask.DelayMs)
+	assert.Positive(t, subtask.HeartbeatInterval)
+
+	beat, err := st.AgentClient.Heartbeat(ctx, &c.HeartbeatRequest{AgentId: agentId, SubtaskId: subtask.SubtaskId})
+	require.NoError(t, err)
+	assert.False(t, beat.LeaseLost)
+
+	_, err = st.AgentClient.SendResult(ctx, &c.SendResultRequest{AgentId: agentId, SubtaskId: subtask.SubtaskId, Result: "6"})
+	require.NoError(t, err)
+	_, err = st.AgentClient.SendResult(ctx, &c.SendResultRequest{AgentId: agentId, SubtaskId: subtask.SubtaskId, Result: "6"})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestAgents_LeaseExpiry(t *testing.T) {
+	ctx, st := test.New(t)
+
+	req := agentTask(ctx, t, st, "2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3", `{"multiplication": 30}`)
+	agentId := registerAgent(ctx, t, st)
+	subtask := ownSubtask(ctx, t, st, agentId, req.TaskId)
+
+	// No heartbeats: the lease expires after leases.ttl and the subtask is
+	// taken away from the agent.
+	require.Eventually(t, func() bool {
+		resp, err := st.CalcClient.GetWorkersInfo(ctx, &c.Empty{})
+		require.NoError(t, err)
+		for _, w := range workersInfo(t, resp.Workers) {
+			if w["remote"] == true && int64(w["id"].(float64)) == agentId {
+				return len(w["running"].([]interface{})) == 0
+			}
+		}
+		return false
+	}, 2*st.Cfg.Leases.TTL, 500*time.Millisecond)
+
+	beat, err := st.AgentClient.Heartbeat(ctx, &c.HeartbeatRequest{AgentId: agentId, SubtaskId: subtask.SubtaskId})
+	require.NoError(t, err)
+	assert.True(t, beat.LeaseLost)
+	_, err = st.AgentClient.SendResult(ctx, &c.SendResultRequest{AgentId: agentId, SubtaskId: subtask.SubtaskId, Result: "6"})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	found := false
+	for _, v := range taskStatus(ctx, t, st, req)["subtasks"].([]interface{}) {
+		if strings.Contains(fmt.Sprint(v.(map[string]interface{})["note"]), "expired") {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+// agentTask adds the task for a new user with the delays. Long delays keep
+// the local workers busy, so they leave some subtasks to the agents.
+func agentTask(ctx context.Context, t *testing.T, st *test.Test, expression, delays string) *c.TaskRequest {
+	user := st.NewUser(ctx)
+	_, err := st.CalcClient.SetDelayOverride(ctx, &c.DelayOverrideRequest{UserId: user, Delays: delays})
+	require.NoError(t, err)
+	resp, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: expression})
+	require.NoError(t, err)
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Task), &task))
+	req := &c.TaskRequest{TaskId: int64(task["id"].(float64)), UserId: user}
+	t.Cleanup(func() {
+		st.CalcClient.CancelTask(ctx, req)
+	})
+	return req
+}
+
+func registerAgent(ctx context.Context, t *testing.T, st *test.Test) int64 {
+	res, err := st.AgentClient.RegisterAgent(ctx, &c.RegisterAgentRequest{Name: fmt.Sprintf("agent%d", time.Now().UnixNano()), Slots: 1})
+	require.NoError(t, err)
+	return res.AgentId
+}
+
+// ownSubtask polls subtasks for the agent until it gets one of the task.
+// Subtasks of the other tests are computed and sent back right away.
+func ownSubtask(ctx context.Context, t *testing.T, st *test.Test, agentId, taskId int64) *c.GetSubtaskResponse {
+	deadline := time.Now().Add(time.Minute)
+	for time.Now().Before(deadline) {
+		subtask, err := st.AgentClient.GetSubtask(ctx, &c.GetSubtaskRequest{AgentId: agentId})
+		require.NoError(t, err)
+		if !subtask.Found {
+			continue
+		}
+		if subtask.TaskId == taskId {
+			return subtask
+		}
+		req := &c.SendResultRequest{AgentId: agentId, SubtaskId: subtask.SubtaskId}
+		if req.Result, err = eval.Eval(subtask.Expression); err != nil {
+			req.Error = err.Error()
+		}
+		st.AgentClient.SendResult(ctx, req)
+	}
+	t.Fatalf("agent %d got no subtask of task %d", agentId, taskId)
+	return nil
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	c "github.com/apple5343/golangProjectV2/proto"
+	"github.com/apple5343/golangProjectV2/tests/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCancelTask(t *testing.T) {
+	ctx, st := test.New(t)
+
+	owner := st.NewUser(ctx)
+	other := st.NewUser(ctx)
+
+	resp, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: owner, Task: "2+2*3"})
+	require.NoError(t, err)
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Task), &task))
+	id := int64(task["id"].(float64))
+
+	_, err = st.CalcClient.CancelTask(ctx, &c.TaskRequest{TaskId: id, UserId: other})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.CalcClient.CancelTask(ctx, &c.TaskRequest{TaskId: id, UserId: owner})
+	require.NoError(t, err)
+
+	_, err = st.CalcClient.CancelTask(ctx, &c.TaskRequest{TaskId: id, UserId: owner})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	got, err := st.CalcClient.GetTask(ctx, &c.GetTaskRequest{TaskId: id, UserId: owner})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(got.Task), &task))
+	assert.Equal(t, "cancelled", task["status"])
+
+	time.Sleep(500 * time.Millisecond)
+	workers, err := st.CalcClient.GetWorkersInfo(ctx, &c.Empty{})
+	require.NoError(t, err)
+	var list []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(workers.Workers), &list))
+	for _, w := range list {
+		assert.NotEqual(t, float64(id), w["expressionId"])
+	}
+
+	_, err = st.CalcClient.CancelTask(ctx, &c.TaskRequest{TaskId: -1, UserId: owner})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/apple5343/golangProjectV2/internal/lib/eval"
+	c "github.com/apple5343/golangProjectV2/proto"
+	"github.com/apple5343/golangProjectV2/tests/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDeadLetters_FailCases(t *testing.T) {
+	ctx, st := test.New(t)
+
+	resp, err := st.CalcClient.GetDeadLetters(ctx, &c.Empty{})
+	require.NoError(t, err)
+	var letters []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Letters), &letters))
+
+	_, err = st.CalcClient.RetryDeadLetter(ctx, &c.DeadLetterRequest{Id: -1, UserId: 1})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.CalcClient.DiscardDeadLetter(ctx, &c.DeadLetterRequest{Id: -1, UserId: 1})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestDeadLetters_Retry(t *testing.T) {
+	ctx, st := test.New(t)
+
+	// The local workers are busy with the long multiplications, the agent
+	// fails every subtask of the task it gets until one of them runs out of
+	// attempts.
+	agentId := registerAgent(ctx, t, st)
+	req := agentTask(ctx, t, st, "2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3", `{"multiplication": 30}`)
+	var letter map[string]interface{}
+	require.Eventually(t, func() bool {
+		subtask, err := st.AgentClient.GetSubtask(ctx, &c.GetSubtaskRequest{AgentId: agentId})
+		require.NoError(t, err)
+		if subtask.Found {
+			result := &c.SendResultRequest{AgentId: agentId, SubtaskId: subtask.SubtaskId}
+			if subtask.TaskId == req.TaskId {
+				result.Error = "agent failure"
+			} else if result.Result, err = eval.Eval(subtask.Expression); err != nil {
+				result.Error = err.Error()
+			}
+			st.AgentClient.SendResult(ctx, result)
+		}
+		letter = deadLetterOf(ctx, t, st, req.TaskId)
+		return letter != nil
+	}, time.Minute, 10*time.Millisecond)
+	assert.Equal(t, float64(st.Cfg.Retries.MaxAttempts), letter["attempts"])
+	assert.Equal(t, "multiplication", letter["operation"])
+	assert.Contains(t, letter["error"], "agent failure")
+	assert.Equal(t, "failed", taskStatus(ctx, t, st, req)["status"])
+
+	// The retried task counts against the concurrent tasks of its owner.
+	_, err := st.CalcClient.SetUserQuota(ctx, &c.SetUserQuotaRequest{UserId: req.UserId, Concurrent: 1})
+	require.NoError(t, err)
+	resp, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: req.UserId, Task: "2*3"})
+	require.NoError(t, err)
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Task), &task))
+	other := &c.TaskRequest{TaskId: int64(task["id"].(float64)), UserId: req.UserId}
+	_, err = st.CalcClient.RetryDeadLetter(ctx, &c.DeadLetterRequest{Id: int64(letter["id"].(float64)), UserId: 1})
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, "failed", taskStatus(ctx, t, st, req)["status"])
+	_, err = st.CalcClient.CancelTask(ctx, other)
+	require.NoError(t, err)
+
+	_, err = st.CalcClient.RetryDeadLetter(ctx, &c.DeadLetterRequest{Id: int64(letter["id"].(float64)), UserId: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "processing", taskStatus(ctx, t, st, req)["status"])
+	assert.Nil(t, deadLetterOf(ctx, t, st, req.TaskId))
+}
+
+// deadLetterOf returns the dead letter of the task or nil.
+func deadLetterOf(ctx context.Context, t *testing.T, st *test.Test, taskId int64) map[string]interface{} {
+	resp, err := st.CalcClient.GetDeadLetters(ctx, &c.Empty{})
+	require.NoError(t, err)
+	var letters []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Letters), &letters))
+	for _, v := range letters {
+		if v["taskId"] == float64(taskId) {
+			return v
+		}
+	}
+	return nil
+}
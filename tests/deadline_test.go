@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	c "github.com/apple5343/golangProjectV2/proto"
+	"github.com/apple5343/golangProjectV2/tests/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDeadline_TimedOut(t *testing.T) {
+	ctx, st := test.New(t)
+
+	user := st.NewUser(ctx)
+
+	resp, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "2+2*3", MaxDuration: "2s"})
+	require.NoError(t, err)
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Task), &task))
+	assert.NotEmpty(t, task["deadline"])
+	req := &c.TaskRequest{TaskId: int64(task["id"].(float64)), UserId: user}
+
+	require.Eventually(t, func() bool {
+		return taskStatus(ctx, t, st, req)["status"] == "timed_out"
+	}, time.Minute, 200*time.Millisecond)
+	info := taskStatus(ctx, t, st, req)
+	require.NotEmpty(t, info["subtasks"])
+	notes := info["subtasks"].([]interface{})
+	assert.Contains(t, notes[len(notes)-1].(map[string]interface{})["note"], "deadline")
+
+	_, err = st.CalcClient.CancelTask(ctx, req)
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestDeadline_FailCases(t *testing.T) {
+	ctx, st := test.New(t)
+
+	user := st.NewUser(ctx)
+
+	tests := []struct {
+		name        string
+		deadline    string
+		maxDuration string
+	}{
+		{name: "Deadline in the past", deadline: time.Now().Add(-time.Hour).Format(time.RFC3339)},
+		{name: "Invalid deadline", deadline: "tomorrow"},
+		{name: "Negative duration", maxDuration: "-5s"},
+		{name: "Invalid duration", maxDuration: "five minutes"},
+		{name: "Both", deadline: time.Now().Add(time.Hour).Format(time.RFC3339), maxDuration: "5s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "2+2", Deadline: tt.deadline, MaxDuration: tt.maxDuration})
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+
+	_, err := st.CalcClient.SetDefaultTimeout(ctx, &c.SetDefaultTimeoutRequest{MaxDuration: "-1s"})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
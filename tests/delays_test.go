@@ -0,0 +1,229 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	c "github.com/apple5343/golangProjectV2/proto"
+	"github.com/apple5343/golangProjectV2/tests/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDelays_Profiles(t *testing.T) {
+	ctx, st := test.New(t)
+
+	_, err := st.CalcClient.UpdateDelays(ctx, &c.UpdateDelaysRequest{
+		Delays:  `{"plus": {"kind": "uniform", "min": 100, "max": 300}}`,
+		Profile: "tests",
+	})
+	require.NoError(t, err)
+
+	resp, err := st.CalcClient.GetDelays(ctx, &c.Empty{})
+	require.NoError(t, err)
+	var delays struct {
+		Delays   map[string]map[string]interface{}            `json:"delays"`
+		Profiles map[string]map[string]map[string]interface{} `json:"profiles"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resp.Delays), &delays))
+	assert.Len(t, delays.Delays, 4)
+	for _, name := range []string{"demo", "realistic", "stress", "tests"} {
+		assert.Contains(t, delays.Profiles, name)
+	}
+	assert.Equal(t, "uniform", delays.Profiles["tests"]["plus"]["kind"])
+	assert.Len(t, delays.Profiles["tests"], 4)
+}
+
+func TestDelays_SwitchProfile(t *testing.T) {
+	ctx, st := test.New(t)
+
+	resp, err := st.CalcClient.GetDelays(ctx, &c.Empty{})
+	require.NoError(t, err)
+	var current struct {
+		Delays json.RawMessage `json:"delays"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resp.Delays), &current))
+	t.Cleanup(func() {
+		_, err := st.CalcClient.UpdateDelays(ctx, &c.UpdateDelaysRequest{Delays: string(current.Delays)})
+		require.NoError(t, err)
+	})
+
+	_, err = st.CalcClient.UpdateDelays(ctx, &c.UpdateDelaysRequest{Profile: "stress"})
+	require.NoError(t, err)
+	resp, err = st.CalcClient.GetDelays(ctx, &c.Empty{})
+	require.NoError(t, err)
+	var delays map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Delays), &delays))
+	assert.Equal(t, "stress", delays["profile"])
+
+	user := st.NewUser(ctx)
+	added, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "2*3+4"})
+	require.NoError(t, err)
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(added.Task), &task))
+	req := &c.TaskRequest{TaskId: int64(task["id"].(float64)), UserId: user}
+
+	require.Eventually(t, func() bool {
+		return taskStatus(ctx, t, st, req)["status"] == "completed"
+	}, 10*time.Second, 100*time.Millisecond)
+	for _, v := range taskStatus(ctx, t, st, req)["subtasks"].([]interface{}) {
+		assert.LessOrEqual(t, v.(map[string]interface{})["delayMs"].(float64), 200.0)
+	}
+}
+
+func TestDelays_SnapshotVersion(t *testing.T) {
+	ctx, st := test.New(t)
+
+	resp, err := st.CalcClient.GetDelays(ctx, &c.Empty{})
+	require.NoError(t, err)
+	var current struct {
+		Delays json.RawMessage `json:"delays"`
+		Mode   string          `json:"mode"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resp.Delays), &current))
+	t.Cleanup(func() {
+		_, err := st.CalcClient.UpdateDelays(ctx, &c.UpdateDelaysRequest{Delays: string(current.Delays)})
+		require.NoError(t, err)
+		_, err = st.CalcClient.SetDelayMode(ctx, &c.SetDelayModeRequest{Mode: current.Mode})
+		require.NoError(t, err)
+	})
+
+	_, err = st.CalcClient.SetDelayMode(ctx, &c.SetDelayModeRequest{Mode: "snapshot"})
+	require.NoError(t, err)
+	_, err = st.CalcClient.UpdateDelays(ctx, &c.UpdateDelaysRequest{Delays: `{"plus": 0.3}`, UserId: 1})
+	require.NoError(t, err)
+	history := delayHistory(ctx, t, st)
+	require.NotEmpty(t, history)
+	started := history[0]
+	assert.Equal(t, "user 1", started["author"])
+
+	user := st.NewUser(ctx)
+	added, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "1+1+1+1"})
+	require.NoError(t, err)
+	_, err = st.CalcClient.UpdateDelays(ctx, &c.UpdateDelaysRequest{Delays: `{"plus": 0.1}`, UserId: 1})
+	require.NoError(t, err)
+	assert.Equal(t, started["id"].(float64)+1, delayHistory(ctx, t, st)[0]["id"])
+
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(added.Task), &task))
+	req := &c.TaskRequest{TaskId: int64(task["id"].(float64)), UserId: user}
+	require.Eventually(t, func() bool {
+		return taskStatus(ctx, t, st, req)["status"] == "completed"
+	}, 10*time.Second, 100*time.Millisecond)
+	info := taskStatus(ctx, t, st, req)
+	assert.Equal(t, started["id"], info["delayVersion"])
+	steps := info["subtasks"].([]interface{})
+	require.Len(t, steps, 3)
+	for _, v := range steps {
+		assert.Equal(t, 300.0, v.(map[string]interface{})["delayMs"])
+	}
+}
+
+func delayHistory(ctx context.Context, t *testing.T, st *test.Test) []map[string]interface{} {
+	resp, err := st.CalcClient.GetDelayHistory(ctx, &c.Empty{})
+	require.NoError(t, err)
+	var versions []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Versions), &versions))
+	return versions
+}
+
+func TestDelays_UserOverrides(t *testing.T) {
+	ctx, st := test.New(t)
+
+	users := make([]int64, 2)
+	for i := range users {
+		users[i] = st.NewUser(ctx)
+	}
+	group := fmt.Sprintf("trainees%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		_, err := st.CalcClient.SetDelayOverride(ctx, &c.DelayOverrideRequest{Group: group})
+		require.NoError(t, err)
+	})
+
+	_, err := st.CalcClient.SetUserDelayGroup(ctx, &c.SetUserDelayGroupRequest{UserId: users[1], Group: group})
+	require.NoError(t, err)
+	_, err = st.CalcClient.SetDelayOverride(ctx, &c.DelayOverrideRequest{Group: group, Delays: `{"plus": 0.2}`})
+	require.NoError(t, err)
+	_, err = st.CalcClient.SetDelayOverride(ctx, &c.DelayOverrideRequest{UserId: users[0], Delays: `{"plus": 0.1}`})
+	require.NoError(t, err)
+
+	tests := []struct {
+		user   int64
+		source string
+		delay  float64
+		group  string
+	}{
+		{users[0], "user", 100, ""},
+		{users[1], "group", 200, group},
+	}
+	for _, tt := range tests {
+		delays := userDelays(ctx, t, st, tt.user)
+		assert.Equal(t, tt.source, delays["sources"].(map[string]interface{})["plus"])
+		assert.Equal(t, "global", delays["sources"].(map[string]interface{})["minus"])
+		assert.Equal(t, tt.delay, delays["delays"].(map[string]interface{})["plus"].(map[string]interface{})["value"])
+		assert.Equal(t, tt.group, delays["group"])
+	}
+
+	added, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: users[0], Task: "1+1"})
+	require.NoError(t, err)
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(added.Task), &task))
+	req := &c.TaskRequest{TaskId: int64(task["id"].(float64)), UserId: users[0]}
+	require.Eventually(t, func() bool {
+		return taskStatus(ctx, t, st, req)["status"] == "completed"
+	}, 10*time.Second, 100*time.Millisecond)
+	steps := taskStatus(ctx, t, st, req)["subtasks"].([]interface{})
+	require.Len(t, steps, 1)
+	assert.Equal(t, 100.0, steps[0].(map[string]interface{})["delayMs"])
+
+	_, err = st.CalcClient.SetDelayOverride(ctx, &c.DelayOverrideRequest{UserId: users[0]})
+	require.NoError(t, err)
+	assert.Equal(t, "global", userDelays(ctx, t, st, users[0])["sources"].(map[string]interface{})["plus"])
+
+	_, err = st.CalcClient.SetDelayOverride(ctx, &c.DelayOverrideRequest{UserId: -1, Delays: `{"plus": 1}`})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = st.CalcClient.SetUserDelayGroup(ctx, &c.SetUserDelayGroupRequest{UserId: -1, Group: group})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func userDelays(ctx context.Context, t *testing.T, st *test.Test, userID int64) map[string]interface{} {
+	resp, err := st.CalcClient.GetUserDelays(ctx, &c.GetUserDelaysRequest{UserId: userID})
+	require.NoError(t, err)
+	var delays map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Delays), &delays))
+	return delays
+}
+
+func TestDelays_FailCases(t *testing.T) {
+	ctx, st := test.New(t)
+
+	tests := []struct {
+		name    string
+		delays  string
+		profile string
+		code    codes.Code
+	}{
+		{"unknown kind", `{"plus": {"kind": "gamma", "mean": 100}}`, "", codes.InvalidArgument},
+		{"negative", `{"plus": -1}`, "", codes.InvalidArgument},
+		{"max less than min", `{"plus": {"kind": "uniform", "min": 300, "max": 100}}`, "", codes.InvalidArgument},
+		{"unknown operation", `{"power": 1}`, "", codes.InvalidArgument},
+		{"unknown profile", "", "missing", codes.NotFound},
+	}
+	_, err := st.CalcClient.SetDelayMode(ctx, &c.SetDelayModeRequest{Mode: "sometimes"})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.CalcClient.UpdateDelays(ctx, &c.UpdateDelaysRequest{Delays: tt.delays, Profile: tt.profile})
+			require.Error(t, err)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
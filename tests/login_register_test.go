@@ -6,8 +6,8 @@ import (
 	"testing"
 	"time"
 
+	s "github.com/apple5343/golangProjectV2/proto"
 	"github.com/apple5343/golangProjectV2/tests/test"
-	s "github.com/apple5343/grpc"
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	c "github.com/apple5343/golangProjectV2/proto"
+	"github.com/apple5343/golangProjectV2/tests/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPauseResume(t *testing.T) {
+	ctx, st := test.New(t)
+
+	user := st.NewUser(ctx)
+
+	resp, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "2+2*3"})
+	require.NoError(t, err)
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Task), &task))
+	req := &c.TaskRequest{TaskId: int64(task["id"].(float64)), UserId: user}
+
+	_, err = st.CalcClient.ResumeTask(ctx, req)
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = st.CalcClient.PauseTask(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "paused", taskStatus(ctx, t, st, req)["status"])
+
+	_, err = st.CalcClient.PauseTask(ctx, req)
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// The first round finishes, the second one is not started.
+	require.Eventually(t, func() bool {
+		return taskStatus(ctx, t, st, req)["lastStep"] == "2+6"
+	}, time.Minute, 200*time.Millisecond)
+	time.Sleep(time.Second)
+	info := taskStatus(ctx, t, st, req)
+	assert.Equal(t, "paused", info["status"])
+	assert.Len(t, info["subtasks"], 1)
+
+	_, err = st.CalcClient.ResumeTask(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "processing", taskStatus(ctx, t, st, req)["status"])
+
+	require.Eventually(t, func() bool {
+		return taskStatus(ctx, t, st, req)["status"] == "completed"
+	}, time.Minute, 200*time.Millisecond)
+	assert.Equal(t, "8", taskStatus(ctx, t, st, req)["result"])
+}
+
+func taskStatus(ctx context.Context, t *testing.T, st *test.Test, req *c.TaskRequest) map[string]interface{} {
+	resp, err := st.CalcClient.GetTask(ctx, &c.GetTaskRequest{TaskId: req.TaskId, UserId: req.UserId})
+	require.NoError(t, err)
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Task), &task))
+	return task
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	c "github.com/apple5343/golangProjectV2/proto"
+	"github.com/apple5343/golangProjectV2/tests/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type queueSnapshot struct {
+	Total        int            `json:"total"`
+	Depth        map[string]int `json:"depth"`
+	OldestWaitMs int64          `json:"oldestWaitMs"`
+	Subtasks     []struct {
+		TaskId    int64  `json:"taskId"`
+		UserId    int64  `json:"userId"`
+		Operation string `json:"operation"`
+		Pool      string `json:"pool"`
+		WaitMs    int64  `json:"waitMs"`
+	} `json:"subtasks"`
+}
+
+func TestQueue_Inspector(t *testing.T) {
+	ctx, st := test.New(t)
+
+	user := st.NewUser(ctx)
+	// More divisions than there are workers in the shared pool.
+	resp, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "1/1+1/1+1/1+1/1+1/1+1/1+1/1+1/1+1/1+1/1"})
+	require.NoError(t, err)
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Task), &task))
+	req := &c.TaskRequest{TaskId: int64(task["id"].(float64)), UserId: user}
+	t.Cleanup(func() {
+		// Cancelled already unless the test failed early.
+		st.CalcClient.CancelTask(ctx, req)
+	})
+
+	var queue queueSnapshot
+	queued := 0
+	require.Eventually(t, func() bool {
+		resp, err := st.CalcClient.GetQueue(ctx, &c.Empty{})
+		require.NoError(t, err)
+		queue = queueSnapshot{}
+		require.NoError(t, json.Unmarshal([]byte(resp.Queue), &queue))
+		queued = 0
+		for _, v := range queue.Subtasks {
+			if v.TaskId == req.TaskId {
+				queued++
+			}
+		}
+		return queued > 0
+	}, 10*time.Second, 200*time.Millisecond)
+
+	assert.GreaterOrEqual(t, queue.Depth["division"], queued)
+	assert.Contains(t, queue.Depth, "plus")
+	assert.Equal(t, len(queue.Subtasks), queue.Total)
+	for i, v := range queue.Subtasks {
+		assert.LessOrEqual(t, v.WaitMs, queue.OldestWaitMs)
+		if i > 0 {
+			assert.LessOrEqual(t, v.WaitMs, queue.Subtasks[i-1].WaitMs)
+		}
+		if v.TaskId == req.TaskId {
+			assert.Equal(t, user, v.UserId)
+			assert.Equal(t, "division", v.Operation)
+			assert.Equal(t, "shared", v.Pool)
+		}
+	}
+
+	_, err = st.CalcClient.CancelTask(ctx, req)
+	require.NoError(t, err)
+	after, err := st.CalcClient.GetQueue(ctx, &c.Empty{})
+	require.NoError(t, err)
+	queue = queueSnapshot{}
+	require.NoError(t, json.Unmarshal([]byte(after.Queue), &queue))
+	for _, v := range queue.Subtasks {
+		assert.NotEqual(t, req.TaskId, v.TaskId)
+	}
+}
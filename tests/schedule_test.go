@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	c "github.com/apple5343/golangProjectV2/proto"
+	"github.com/apple5343/golangProjectV2/tests/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSchedule_RunOnce(t *testing.T) {
+	ctx, st := test.New(t)
+
+	user := st.NewUser(ctx)
+
+	resp, err := st.CalcClient.AddSchedule(ctx, &c.ScheduleRequest{UserId: user, Expression: "2+2",
+		RunAt: time.Now().Add(2 * time.Second).Format(time.RFC3339)})
+	require.NoError(t, err)
+	var schedule map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Schedule), &schedule))
+	assert.Equal(t, "active", schedule["status"])
+	assert.Equal(t, "once", schedule["catchUp"])
+
+	var list []map[string]interface{}
+	require.Eventually(t, func() bool {
+		resp, err := st.CalcClient.GetSchedules(ctx, &c.ScheduleRequest{UserId: user})
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal([]byte(resp.Schedules), &list))
+		return len(list) == 1 && list[0]["status"] == "done"
+	}, 30*time.Second, 200*time.Millisecond)
+	taskId := int64(list[0]["lastTaskId"].(float64))
+	require.NotZero(t, taskId)
+
+	task := taskStatus(ctx, t, st, &c.TaskRequest{TaskId: taskId, UserId: user})
+	assert.Equal(t, "2+2", task["expression"])
+}
+
+func TestSchedule_Recurring(t *testing.T) {
+	ctx, st := test.New(t)
+
+	user := st.NewUser(ctx)
+	other := st.NewUser(ctx)
+
+	resp, err := st.CalcClient.AddSchedule(ctx, &c.ScheduleRequest{UserId: user, Expression: "2*3", Cron: "0 8 * * 1-5", CatchUp: "skip"})
+	require.NoError(t, err)
+	var schedule map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Schedule), &schedule))
+	assert.Contains(t, schedule["nextRun"], " 08:00:00")
+	req := &c.ScheduleRequest{UserId: user, ScheduleId: int64(schedule["id"].(float64))}
+
+	_, err = st.CalcClient.ResumeSchedule(ctx, req)
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = st.CalcClient.PauseSchedule(ctx, req)
+	require.NoError(t, err)
+	_, err = st.CalcClient.PauseSchedule(ctx, req)
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = st.CalcClient.ResumeSchedule(ctx, req)
+	require.NoError(t, err)
+
+	resp, err = st.CalcClient.UpdateSchedule(ctx, &c.ScheduleRequest{UserId: user, ScheduleId: req.ScheduleId, Expression: "3*3", Cron: "@hourly"})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(resp.Schedule), &schedule))
+	assert.Equal(t, "3*3", schedule["expression"])
+	assert.Contains(t, schedule["nextRun"], ":00:00")
+
+	_, err = st.CalcClient.DeleteSchedule(ctx, &c.ScheduleRequest{UserId: other, ScheduleId: req.ScheduleId})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.CalcClient.DeleteSchedule(ctx, req)
+	require.NoError(t, err)
+	list, err := st.CalcClient.GetSchedules(ctx, &c.ScheduleRequest{UserId: user})
+	require.NoError(t, err)
+	assert.Equal(t, "[]", list.Schedules)
+}
+
+func TestSchedule_FailCases(t *testing.T) {
+	ctx, st := test.New(t)
+
+	user := st.NewUser(ctx)
+	later := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name     string
+		request  *c.ScheduleRequest
+		expected string
+	}{
+		{name: "No time", request: &c.ScheduleRequest{Expression: "2+2"}, expected: "either a run time or a cron expression must be set"},
+		{name: "Both times", request: &c.ScheduleRequest{Expression: "2+2", RunAt: later, Cron: "@daily"}, expected: "either a run time or a cron expression must be set"},
+		{name: "Past run time", request: &c.ScheduleRequest{Expression: "2+2", RunAt: time.Now().Add(-time.Hour).Format(time.RFC3339)}, expected: "run time must be in the future"},
+		{name: "Invalid cron", request: &c.ScheduleRequest{Expression: "2+2", Cron: "61 * * * *"}, expected: "invalid cron expression"},
+		{name: "Short cron", request: &c.ScheduleRequest{Expression: "2+2", Cron: "* * *"}, expected: "invalid cron expression"},
+		{name: "Invalid catch-up", request: &c.ScheduleRequest{Expression: "2+2", Cron: "@daily", CatchUp: "never"}, expected: "catch-up policy must be skip, once or all"},
+		{name: "Invalid expression", request: &c.ScheduleRequest{Expression: "2+", Cron: "@daily"}, expected: "invalid expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.UserId = user
+			_, err := st.CalcClient.AddSchedule(ctx, tt.request)
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
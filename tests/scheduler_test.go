@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"testing"
+
+	c "github.com/apple5343/golangProjectV2/proto"
+	"github.com/apple5343/golangProjectV2/tests/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQuorum_FailCases(t *testing.T) {
+	ctx, st := test.New(t)
+
+	_, err := st.CalcClient.SetQuorum(ctx, &c.SetQuorumRequest{All: true, Size: 1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "quorum size must be at least 2")
+
+	_, err = st.CalcClient.SetQuorum(ctx, &c.SetQuorumRequest{Operations: []string{"power"}, Size: 3})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown operation")
+}
+
+func TestPriority_FailCases(t *testing.T) {
+	ctx, st := test.New(t)
+
+	user := st.NewUser(ctx)
+
+	_, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "2+2", Priority: "urgent"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown priority")
+
+	_, err = st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "2+2", Priority: "high"})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.CalcClient.SetUserWeight(ctx, &c.SetUserWeightRequest{UserId: user, Weight: 0})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "weight must be between 1 and 100")
+
+	_, err = st.CalcClient.SetUserWeight(ctx, &c.SetUserWeightRequest{UserId: -1, Weight: 2})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.CalcClient.SetUserWeight(ctx, &c.SetUserWeightRequest{UserId: user, Weight: 2})
+	require.NoError(t, err)
+}
+
+func TestQuota(t *testing.T) {
+	ctx, st := test.New(t)
+
+	user := st.NewUser(ctx)
+
+	_, err := st.CalcClient.SetUserQuota(ctx, &c.SetUserQuotaRequest{UserId: user, Concurrent: -2, PerHour: -1, DelayPerDay: -1})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.CalcClient.SetUserQuota(ctx, &c.SetUserQuotaRequest{UserId: -1, Concurrent: 1, PerHour: -1, DelayPerDay: -1})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.CalcClient.SetUserQuota(ctx, &c.SetUserQuotaRequest{UserId: user, Concurrent: 1, PerHour: 0, DelayPerDay: 0})
+	require.NoError(t, err)
+
+	_, err = st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "2+2"})
+	require.NoError(t, err)
+
+	_, err = st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "2+2"})
+	require.Error(t, err)
+	st2 := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st2.Code())
+	require.Len(t, st2.Details(), 1)
+	info, ok := st2.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	assert.Positive(t, info.RetryDelay.AsDuration())
+
+	_, err = st.CalcClient.SetUserQuota(ctx, &c.SetUserQuotaRequest{UserId: user, Concurrent: 0, PerHour: 1, DelayPerDay: 0})
+	require.NoError(t, err)
+
+	_, err = st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "2+2"})
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Contains(t, err.Error(), "tasks per hour")
+}
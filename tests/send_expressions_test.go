@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"testing"
 
+	c "github.com/apple5343/golangProjectV2/proto"
 	"github.com/apple5343/golangProjectV2/tests/test"
-	c "github.com/apple5343/grpc"
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
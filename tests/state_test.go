@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	c "github.com/apple5343/golangProjectV2/proto"
+	"github.com/apple5343/golangProjectV2/tests/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusHistory(t *testing.T) {
+	ctx, st := test.New(t)
+
+	user := st.NewUser(ctx)
+	by := fmt.Sprintf("user %d", user)
+
+	resp, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "2+2"})
+	require.NoError(t, err)
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Task), &task))
+	assert.Equal(t, "queued", task["status"])
+	completed := &c.TaskRequest{TaskId: int64(task["id"].(float64)), UserId: user}
+
+	resp, err = st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "3+3"})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(resp.Task), &task))
+	cancelled := &c.TaskRequest{TaskId: int64(task["id"].(float64)), UserId: user}
+	_, err = st.CalcClient.CancelTask(ctx, cancelled)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return taskStatus(ctx, t, st, completed)["status"] == "completed"
+	}, time.Minute, 200*time.Millisecond)
+
+	history := statusHistory(t, taskStatus(ctx, t, st, completed))
+	require.Len(t, history, 3)
+	assert.Equal(t, map[string]string{"from": "", "to": "queued", "by": by}, history[0])
+	assert.Equal(t, "queued", history[1]["from"])
+	assert.Equal(t, "processing", history[1]["to"])
+	assert.Regexp(t, `^worker \d+$`, history[1]["by"])
+	assert.Equal(t, map[string]string{"from": "processing", "to": "completed", "by": "system"}, history[2])
+
+	history = statusHistory(t, taskStatus(ctx, t, st, cancelled))
+	require.NotEmpty(t, history)
+	last := history[len(history)-1]
+	assert.Equal(t, "cancelled", last["to"])
+	assert.Equal(t, by, last["by"])
+
+	// Finished tasks do not change.
+	_, err = st.CalcClient.PauseTask(ctx, completed)
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = st.CalcClient.CancelTask(ctx, cancelled)
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+// statusHistory returns the status history of the task without the times.
+func statusHistory(t *testing.T, task map[string]interface{}) []map[string]string {
+	list, ok := task["history"].([]interface{})
+	require.True(t, ok)
+	result := []map[string]string{}
+	for _, v := range list {
+		entry := v.(map[string]interface{})
+		assert.NotEmpty(t, entry["time"])
+		result = append(result, map[string]string{"from": entry["from"].(string), "to": entry["to"].(string), "by": entry["by"].(string)})
+	}
+	return result
+}
@@ -8,16 +8,18 @@ import (
 	"testing"
 
 	"github.com/apple5343/golangProjectV2/internal/config"
-	s "github.com/apple5343/grpc"
+	s "github.com/apple5343/golangProjectV2/proto"
+	"github.com/brianvoe/gofakeit/v7"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 type Test struct {
 	*testing.T
-	Cfg        *config.Config
-	AuthClient s.AuthClient
-	CalcClient s.CalculatorClient
+	Cfg         *config.Config
+	AuthClient  s.AuthClient
+	CalcClient  s.CalculatorClient
+	AgentClient s.AgentClient
 }
 
 const (
@@ -47,13 +49,27 @@ func New(t *testing.T) (context.Context, *Test) {
 	}
 
 	return ctx, &Test{
-		T:          t,
-		Cfg:        cfg,
-		AuthClient: s.NewAuthClient(cc),
-		CalcClient: s.NewCalculatorClient(cc),
+		T:           t,
+		Cfg:         cfg,
+		AuthClient:  s.NewAuthClient(cc),
+		CalcClient:  s.NewCalculatorClient(cc),
+		AgentClient: s.NewAgentClient(cc),
 	}
 }
 
+// NewUser registers a new user and returns its id.
+func (t *Test) NewUser(ctx context.Context) int64 {
+	t.Helper()
+	user, err := t.AuthClient.Register(ctx, &s.RegisterRequest{
+		Name:     gofakeit.Email(),
+		Password: gofakeit.Regex(`[A-Z]{2}[a-z]{4}[0-9]{2}[!@#$%^&*]{2}`),
+	})
+	if err != nil {
+		t.Fatalf("user registration failed: %v", err)
+	}
+	return user.GetUserId()
+}
+
 func grpcAddress(cfg *config.Config) string {
 	return net.JoinHostPort(grpcHost, strconv.Itoa(cfg.GRPC.Port))
 }
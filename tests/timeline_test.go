@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	c "github.com/apple5343/golangProjectV2/proto"
+	"github.com/apple5343/golangProjectV2/tests/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepTiming(t *testing.T) {
+	ctx, st := test.New(t)
+
+	user := st.NewUser(ctx)
+
+	resp, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "2*3+4"})
+	require.NoError(t, err)
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Task), &task))
+	req := &c.TaskRequest{TaskId: int64(task["id"].(float64)), UserId: user}
+
+	require.Eventually(t, func() bool {
+		return taskStatus(ctx, t, st, req)["status"] == "completed"
+	}, time.Minute, 200*time.Millisecond)
+	info := taskStatus(ctx, t, st, req)
+
+	steps := info["subtasks"].([]interface{})
+	require.Len(t, steps, 2)
+	const layout = "2006-01-02 15:04:05.000"
+	for _, v := range steps {
+		step := v.(map[string]interface{})
+		assert.NotZero(t, step["workerId"])
+		assert.Equal(t, "local", step["agent"])
+		dispatched, err := time.Parse(layout, step["dispatched"].(string))
+		require.NoError(t, err)
+		started, err := time.Parse(layout, step["started"].(string))
+		require.NoError(t, err)
+		finished, err := time.Parse(layout, step["finished"].(string))
+		require.NoError(t, err)
+		assert.False(t, started.Before(dispatched))
+		assert.False(t, finished.Before(started))
+		delay := time.Duration(step["delayMs"].(float64)) * time.Millisecond
+		assert.InDelta(t, delay.Milliseconds(), finished.Sub(started).Milliseconds(), 1000)
+	}
+
+	timeline := info["timeline"].(map[string]interface{})
+	assert.Equal(t, float64(2), timeline["rounds"])
+	path := timeline["criticalPath"].(map[string]interface{})
+	assert.Equal(t, timeline["computeMs"], path["computeMs"])
+	assert.Equal(t, timeline["queueWaitMs"], path["queueWaitMs"])
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	c "github.com/apple5343/golangProjectV2/proto"
+	"github.com/apple5343/golangProjectV2/tests/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdog_HealthyTask(t *testing.T) {
+	ctx, st := test.New(t)
+
+	user := st.NewUser(ctx)
+
+	resp, err := st.CalcClient.AddTask(ctx, &c.AddTaskRequest{UserId: user, Task: "1+1"})
+	require.NoError(t, err)
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resp.Task), &task))
+	id := task["id"].(float64)
+	req := &c.TaskRequest{TaskId: int64(id), UserId: user}
+
+	require.Eventually(t, func() bool {
+		return taskStatus(ctx, t, st, req)["status"] == "completed"
+	}, time.Minute, 200*time.Millisecond)
+
+	stalled, err := st.CalcClient.GetStalledTasks(ctx, &c.Empty{})
+	require.NoError(t, err)
+	var stalls []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(stalled.Tasks), &stalls))
+	for _, v := range stalls {
+		assert.NotEqual(t, id, v["taskId"])
+	}
+	for _, v := range statusHistory(t, taskStatus(ctx, t, st, req)) {
+		assert.NotEqual(t, "blocked", v["to"])
+	}
+}
+
+func TestWatchdog_StalledTask(t *testing.T) {
+	ctx, st := test.New(t)
+
+	// The agent keeps the lease of one subtask alive but never reports it,
+	// the local workers compute the others.
+	agentId := registerAgent(ctx, t, st)
+	req := agentTask(ctx, t, st, "2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3", `{"multiplication": 0.1, "plus": 0.1}`)
+	subtask := ownSubtask(ctx, t, st, agentId, req.TaskId)
+	stop := make(chan struct{})
+	t.Cleanup(func() {
+		close(stop)
+	})
+	go func() {
+		ticker := time.NewTicker(time.Duration(subtask.HeartbeatInterval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			res, err := st.AgentClient.Heartbeat(ctx, &c.HeartbeatRequest{AgentId: agentId, SubtaskId: subtask.SubtaskId})
+			if err != nil || res.LeaseLost {
+				return
+			}
+		}
+	}()
+
+	var stall map[string]interface{}
+	require.Eventually(t, func() bool {
+		stalled, err := st.CalcClient.GetStalledTasks(ctx, &c.Empty{})
+		require.NoError(t, err)
+		var stalls []map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(stalled.Tasks), &stalls))
+		for _, v := range stalls {
+			if v["taskId"] == float64(req.TaskId) {
+				stall = v
+				return true
+			}
+		}
+		return false
+	}, 2*time.Minute, time.Second)
+	assert.Equal(t, float64(req.UserId), stall["userId"])
+	assert.Positive(t, stall["attempts"])
+
+	// The copy queued by the watchdog completes the task.
+	require.Eventually(t, func() bool {
+		return taskStatus(ctx, t, st, req)["status"] == "completed"
+	}, 30*time.Second, 200*time.Millisecond)
+	assert.Contains(t, statusHistory(t, taskStatus(ctx, t, st, req)), map[string]string{"from": "processing", "to": "blocked", "by": "watchdog"})
+}
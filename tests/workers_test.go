@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/apple5343/golangProjectV2/proto"
+	"github.com/apple5343/golangProjectV2/tests/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkers_AddRemove(t *testing.T) {
+	ctx, st := test.New(t)
+
+	before, err := st.CalcClient.GetWorkersInfo(ctx, &c.Empty{})
+	require.NoError(t, err)
+	known := map[float64]bool{}
+	for _, w := range workersInfo(t, before.Workers) {
+		if w["remote"] != true {
+			known[w["id"].(float64)] = true
+		}
+	}
+
+	resp, err := st.CalcClient.AddWorkers(ctx, &c.AddWorkersRequest{Count: 2})
+	require.NoError(t, err)
+	workers := workersInfo(t, resp.Workers)
+	require.True(t, len(workers) >= 3)
+	added := []int64{}
+	for _, w := range workers {
+		assert.Contains(t, []string{"idle", "busy", "draining", "dead", "offline", "quarantined"}, w["status"])
+		assert.Contains(t, w, "stats")
+		assert.Contains(t, w["stats"], "crashes")
+		if w["remote"] != true && !known[w["id"].(float64)] {
+			added = append(added, int64(w["id"].(float64)))
+		}
+	}
+	require.Len(t, added, 2)
+
+	_, err = st.CalcClient.DrainWorker(ctx, &c.WorkerRequest{WorkerId: added[0]})
+	require.NoError(t, err)
+	_, err = st.CalcClient.RemoveWorker(ctx, &c.WorkerRequest{WorkerId: added[1]})
+	require.NoError(t, err)
+}
+
+func workersInfo(t *testing.T, js string) []map[string]interface{} {
+	var workers []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(js), &workers))
+	return workers
+}
+
+func TestWorkers_FailCases(t *testing.T) {
+	ctx, st := test.New(t)
+
+	_, err := st.CalcClient.AddWorkers(ctx, &c.AddWorkersRequest{Count: 0})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "workers count must be positive")
+
+	_, err = st.CalcClient.AddWorkers(ctx, &c.AddWorkersRequest{Count: 1, Pool: "unknown"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pool not found")
+
+	_, err = st.CalcClient.RemoveWorker(ctx, &c.WorkerRequest{WorkerId: -1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "worker not found")
+
+	_, err = st.CalcClient.DrainWorker(ctx, &c.WorkerRequest{WorkerId: -1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "worker not found")
+
+	_, err = st.CalcClient.UnquarantineWorker(ctx, &c.WorkerRequest{WorkerId: -1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "worker is not quarantined")
+}
+
+func TestWorkers_RetryFailedAttempt(t *testing.T) {
+	ctx, st := test.New(t)
+
+	agentId := registerAgent(ctx, t, st)
+	req := agentTask(ctx, t, st, "2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3+2*3", `{"multiplication": 0.1, "plus": 0.1}`)
+	subtask := ownSubtask(ctx, t, st, agentId, req.TaskId)
+	_, err := st.AgentClient.SendResult(ctx, &c.SendResultRequest{AgentId: agentId, SubtaskId: subtask.SubtaskId, Error: "evaluation failed"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return taskStatus(ctx, t, st, req)["status"] == "completed"
+	}, 30*time.Second, 200*time.Millisecond)
+	info := taskStatus(ctx, t, st, req)
+	assert.Equal(t, "72", info["result"])
+	found := false
+	for _, v := range info["subtasks"].([]interface{}) {
+		if strings.Contains(fmt.Sprint(v.(map[string]interface{})["note"]), "attempt 1 of") {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}